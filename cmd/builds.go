@@ -0,0 +1,25 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"github.com/spf13/cobra"
+)
+
+// buildsCmd represents the builds command
+var buildsCmd = &cobra.Command{
+	Use:     "builds",
+	Aliases: []string{"build"},
+	Short:   "Manage Codefresh builds",
+	Long:    `Manage Codefresh builds`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return clierror.Errorf(clierror.Usage, "Provide item to the builds command")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(buildsCmd)
+}
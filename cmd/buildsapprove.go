@@ -0,0 +1,52 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// buildsApproveCmd represents the builds approve command
+var buildsApproveCmd = &cobra.Command{
+	Use:   "approve <id>",
+	Short: "Approve a build",
+	Long:  `Approve the pending approval step of a build so it continues`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := newService()
+		if err != nil {
+			return err
+		}
+		if err := s.ApproveBuild(cmdCtx, args[0], true); err != nil {
+			return err
+		}
+		lgr.Info("Approved build", "Build-ID", args[0])
+		return nil
+	},
+}
+
+// buildsDenyCmd represents the builds deny command
+var buildsDenyCmd = &cobra.Command{
+	Use:   "deny <id>",
+	Short: "Deny a build",
+	Long:  `Deny the pending approval step of a build`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := newService()
+		if err != nil {
+			return err
+		}
+		if err := s.ApproveBuild(cmdCtx, args[0], false); err != nil {
+			return err
+		}
+		lgr.Info("Denied build", "Build-ID", args[0])
+		return nil
+	},
+}
+
+func init() {
+	buildsCmd.AddCommand(buildsApproveCmd)
+	buildsCmd.AddCommand(buildsDenyCmd)
+}
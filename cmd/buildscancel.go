@@ -0,0 +1,33 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// buildsCancelCmd represents the builds cancel command
+var buildsCancelCmd = &cobra.Command{
+	Use:     "cancel <id>",
+	Aliases: []string{"terminate"},
+	Short:   "Cancel a build",
+	Long:    `Terminate a build that did not finish yet`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := newService()
+		if err != nil {
+			return err
+		}
+		if err := s.CancelBuild(cmdCtx, args[0]); err != nil {
+			return err
+		}
+		lgr.Info("Cancelled build", "Build-ID", args[0])
+		return nil
+	},
+}
+
+func init() {
+	buildsCmd.AddCommand(buildsCancelCmd)
+}
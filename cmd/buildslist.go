@@ -0,0 +1,68 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"time"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/logic"
+	"github.com/spf13/cobra"
+)
+
+type buildsListCmdOptions struct {
+	logic.ListBuildsOptions
+	since string
+	until string
+}
+
+var buildsListOptions = &buildsListCmdOptions{}
+
+// buildsListCmd represents the builds list command
+var buildsListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List builds",
+	Long:    `List the build history, newest first, filtered by pipeline, status, branch, runtime environment and creation time`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		now := time.Now()
+		opt := buildsListOptions.ListBuildsOptions
+		var err error
+		if buildsListOptions.since != "" {
+			if opt.Since, err = logic.ParseTime(buildsListOptions.since, now); err != nil {
+				return err
+			}
+		}
+		if buildsListOptions.until != "" {
+			if opt.Until, err = logic.ParseTime(buildsListOptions.until, now); err != nil {
+				return err
+			}
+		}
+		s, err := newService()
+		if err != nil {
+			return err
+		}
+		builds, truncated, err := s.ListBuilds(cmdCtx, &opt)
+		if err != nil {
+			return err
+		}
+		if truncated {
+			lgr.Warn("Stopped reading the build history, older builds are not listed, raise --max-pages or narrow --since", "Pages", opt.MaxPages)
+		}
+		return printResult(builds)
+	},
+}
+
+func init() {
+	buildsListCmd.Flags().StringVar(&buildsListOptions.Pipeline, "pipeline", "", "Only builds of this pipeline (project/pipeline)")
+	buildsListCmd.Flags().StringVar(&buildsListOptions.Status, "status", "", "Only builds with this status, e.g. success, error, running")
+	buildsListCmd.Flags().StringVar(&buildsListOptions.Branch, "branch", "", "Only builds of this branch")
+	buildsListCmd.Flags().StringVar(&buildsListOptions.RuntimeEnvironment, "runtime-environment", "", "Only builds that ran on this runtime environment")
+	buildsListCmd.Flags().StringVar(&buildsListOptions.since, "since", "", "Only builds created after this time, a duration (24h), a date (2006-01-02) or RFC3339")
+	buildsListCmd.Flags().StringVar(&buildsListOptions.until, "until", "", "Only builds created before this time, a duration (24h), a date (2006-01-02) or RFC3339")
+	buildsListCmd.Flags().IntVar(&buildsListOptions.Limit, "limit", 20, "Maximal number of builds, 0 for all")
+	buildsListCmd.Flags().IntVar(&buildsListOptions.MaxPages, "max-pages", 10, "Maximal number of pages of 100 builds of the history that are read, 0 to read the whole history")
+	buildsCmd.AddCommand(buildsListCmd)
+}
@@ -0,0 +1,36 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/logic"
+	"github.com/spf13/cobra"
+)
+
+var buildsLogsOptions = &logic.LogsOptions{Interval: 2 * time.Second}
+
+// buildsLogsCmd represents the builds logs command
+var buildsLogsCmd = &cobra.Command{
+	Use:   "logs <id>",
+	Short: "Print the logs of a build",
+	Long:  `Print the output of the build steps, with a header when a step starts and a footer when it finishes`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := newService()
+		if err != nil {
+			return err
+		}
+		return s.StreamLogs(cmdCtx, args[0], os.Stdout, buildsLogsOptions)
+	},
+}
+
+func init() {
+	buildsLogsCmd.Flags().BoolVarP(&buildsLogsOptions.Follow, "follow", "f", false, "Keep streaming the logs until the build finishes")
+	buildsLogsCmd.Flags().StringVar(&buildsLogsOptions.Step, "step", "", "Only the logs of this step")
+	buildsCmd.AddCommand(buildsLogsCmd)
+}
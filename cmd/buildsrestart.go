@@ -0,0 +1,36 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var buildsRestartFromFailed bool
+
+// buildsRestartCmd represents the builds restart command
+var buildsRestartCmd = &cobra.Command{
+	Use:   "restart <id>",
+	Short: "Restart a build",
+	Long:  `Start a new build with the parameters of a finished build and print it`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := newService()
+		if err != nil {
+			return err
+		}
+		build, err := s.RestartBuild(cmdCtx, args[0], buildsRestartFromFailed)
+		if err != nil {
+			return err
+		}
+		lgr.Info("Restarted build", "Build-ID", args[0], "New-Build-ID", build.ID)
+		return printResult(build)
+	},
+}
+
+func init() {
+	buildsRestartCmd.Flags().BoolVar(&buildsRestartFromFailed, "from-failed", false, "Continue from the step that failed instead of the first step")
+	buildsCmd.AddCommand(buildsRestartCmd)
+}
@@ -1,11 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"os/user"
 	"path"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/codefresh-io/go-sdk/pkg/codefresh"
 	sdkUtils "github.com/codefresh-io/go-sdk/pkg/utils"
@@ -14,7 +20,20 @@ import (
 	"github.com/codefresh-io/venona/venonactl/pkg/logger"
 	"github.com/codefresh-io/venona/venonactl/pkg/plugins"
 	"github.com/codefresh-io/venona/venonactl/pkg/store"
-	"github.com/olekukonko/tablewriter"
+	"github.com/mattn/go-isatty"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/sharon-vendrov/sharoncli/pkg/apirecord"
+	"github.com/sharon-vendrov/sharoncli/pkg/apiretry"
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"github.com/sharon-vendrov/sharoncli/pkg/hooks"
+	"github.com/sharon-vendrov/sharoncli/pkg/logging"
+	"github.com/sharon-vendrov/sharoncli/pkg/logic"
+	"github.com/sharon-vendrov/sharoncli/pkg/printer"
+	"github.com/sharon-vendrov/sharoncli/pkg/progress"
+	"github.com/sharon-vendrov/sharoncli/pkg/state"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var (
@@ -25,7 +44,18 @@ var (
 	// to prevent version checking during development
 	localDevFlow = "false"
 
-	verbose bool
+	// lgr is the logger of the current run, created before any command runs
+	lgr logger.Logger
+	// console is stderr, shared by the console log and the progress of long commands
+	console = progress.NewConsole(os.Stderr)
+
+	logFile     string
+	logFormat   string
+	logLevel    string
+	logMaxFiles int
+	logMaxAge   time.Duration
+
+	outputFormat string
 
 	configPath string
 	cfAPIHost  string
@@ -34,6 +64,18 @@ var (
 
 	kubeConfigPath string
 
+	recordAPIDir string
+	replayAPIDir string
+	apiRetries   int
+
+	commandTimeout time.Duration
+	// cmdCtx is done once the command was interrupted with SIGINT or SIGTERM or ran longer than --timeout
+	cmdCtx = context.Background()
+	// stopCommand releases cmdCtx
+	stopCommand = func() {}
+	// defaultTimeouts are the timeouts of the commands that stop by themselves when --timeout is not set
+	defaultTimeouts = map[*cobra.Command]time.Duration{}
+
 	skipVerionCheck bool
 )
 
@@ -89,13 +131,22 @@ func extendStoreWithCodefershClient(logger logger.Logger) error {
 	}
 
 	if cfAPIHost == "" && cfAPIToken == "" {
-		context, err := sdkUtils.ReadAuthContext(configPath, cfContext)
-		if err != nil {
-			return err
+		context, err := readCodefreshContext(configPath, cfContext)
+		switch {
+		case (err != nil || context == nil) && replayAPIDir != "":
+			// the recordings answer every request, the credentials are not used
+			cfAPIHost = "https://g.codefresh.io"
+			cfAPIToken = apirecord.Redacted
+			logger.Debug("No codefresh context, replaying the API without credentials")
+		case err != nil:
+			return clierror.Errorf(clierror.Config, "Failed to read codefresh config file %s: %v", configPath, err)
+		case context == nil:
+			return clierror.Errorf(clierror.Config, "Codefresh context %q was not found in %s", cfContext, configPath)
+		default:
+			cfAPIHost = context.URL
+			cfAPIToken = context.Token
+			logger.Debug("Using codefresh context", "Context-Name", context.Name, "Host", cfAPIHost)
 		}
-		cfAPIHost = context.URL
-		cfAPIToken = context.Token
-		logger.Debug("Using codefresh context", "Context-Name", context.Name, "Host", cfAPIHost)
 	} else {
 		logger.Debug("Reading creentials from environment variables")
 		if cfAPIHost == "" {
@@ -103,7 +154,7 @@ func extendStoreWithCodefershClient(logger logger.Logger) error {
 		}
 	}
 
-	logger.Debug("Creating codefresh client", "host", cfAPIHost, "token", cfAPIToken)
+	logger.Debug("Creating codefresh client", "host", cfAPIHost)
 
 	client := codefresh.New(&codefresh.ClientOptions{
 		Auth: codefresh.AuthOptions{
@@ -120,6 +171,23 @@ func extendStoreWithCodefershClient(logger logger.Logger) error {
 	return nil
 }
 
+// cfConfigPath is the codefresh config file the commands read the context from
+func cfConfigPath() string {
+	if configPath != "" {
+		return configPath
+	}
+	return fmt.Sprintf("%s/.cfconfig", os.Getenv("HOME"))
+}
+
+// readCodefreshContext reads the context of the codefresh config file, the current context when name is empty.
+// The sdk prints to stdout when the file is missing, so it is only called for a file that exists
+func readCodefreshContext(path string, name string) (*sdkUtils.CFContext, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	return sdkUtils.ReadAuthContext(path, name)
+}
+
 func extendStoreWithKubeClient(logger logger.Logger) {
 	s := store.GetStore()
 	if kubeConfigPath == "" {
@@ -142,23 +210,174 @@ func isUsingDefaultStorageClass(sc string) bool {
 	return strings.HasPrefix(sc, plugins.DefaultStorageClassNamePrefix)
 }
 
-func dieOnError(err error) {
-	if err != nil {
-		fmt.Printf("Error: %s", err.Error())
-		os.Exit(1)
+// newService creates the logic service with the codefresh client of the store and a cfapi client of its context
+func newService() (*logic.Service, error) {
+	if err := extendStoreWithCodefershClient(lgr); err != nil {
+		return nil, err
+	}
+	api := store.GetStore().CodefreshAPI
+	if api.Token == "" {
+		return nil, clierror.Errorf(clierror.Auth, "Codefresh context has no token, run codefresh auth create-context")
 	}
+	return logic.New(&logic.Options{
+		Codefresh: api.Client,
+		API:       cfapi.New(&cfapi.Options{Host: api.Host, Token: api.Token}),
+		Host:      api.Host,
+	}), nil
 }
 
-func createTable() *tablewriter.Table {
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetBorder(false)
-	table.SetAlignment(tablewriter.ALIGN_LEFT)
-	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
-	table.SetRowLine(false)
-	table.SetHeaderLine(false)
-	table.SetColumnSeparator(" ")
-	table.SetColWidth(100)
-	return table
+// setupAPITransport retries, records or replays the requests of the codefresh clients. The sdk and venonactl
+// send them through http.DefaultTransport, so it is replaced with a transport that only changes the requests
+// to the Codefresh API host, the others like the version check go to the network as before.
+// The recordings have the responses after the retries, so the replay does not retry
+func setupAPITransport() error {
+	if apiRetries < 0 {
+		return clierror.Errorf(clierror.Usage, "api-retries cannot be negative")
+	}
+	transport := http.DefaultTransport
+	if apiRetries > 0 {
+		transport = apiretry.New(&apiretry.Options{
+			Retries:   apiRetries,
+			Transport: transport,
+			Logger:    lgr,
+		})
+	}
+	switch {
+	case recordAPIDir != "" && replayAPIDir != "":
+		return clierror.Errorf(clierror.Usage, "Cannot use both flags record-api and replay-api")
+	case recordAPIDir != "":
+		r, err := apirecord.NewRecorder(&apirecord.Options{
+			Dir:       recordAPIDir,
+			Transport: transport,
+			Logger:    lgr,
+		})
+		if err != nil {
+			return clierror.Errorf(clierror.Config, "Failed to record the API to %s: %v", recordAPIDir, err)
+		}
+		transport = r
+		lgr.Debug("Recording API requests", "Dir", recordAPIDir)
+	case replayAPIDir != "":
+		r, err := apirecord.NewReplayer(&apirecord.Options{
+			Dir:    replayAPIDir,
+			Logger: lgr,
+		})
+		if err != nil {
+			return clierror.Errorf(clierror.Config, "Failed to replay the API from %s: %v", replayAPIDir, err)
+		}
+		transport = r
+		lgr.Debug("Replaying API requests", "Dir", replayAPIDir)
+	}
+	http.DefaultTransport = &apiTransport{
+		api:   transport,
+		other: http.DefaultTransport,
+	}
+	return nil
+}
+
+// apiTransport sends the requests to the host of the codefresh context through api and the others through other
+type apiTransport struct {
+	api   http.RoundTripper
+	other http.RoundTripper
+}
+
+func (t *apiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if u, err := url.Parse(cfAPIHost); err == nil && u.Host != "" && req.URL.Host == u.Host {
+		return t.api.RoundTrip(req)
+	}
+	return t.other.RoundTrip(req)
+}
+
+// newCommandContext creates the context of the command: it is cancelled on the first SIGINT or SIGTERM,
+// so the command can stop and clean up, and a second one exits right away. The returned func releases it
+func newCommandContext(cmd *cobra.Command) (context.Context, func()) {
+	timeout := commandTimeout
+	if !cmd.Flags().Changed("timeout") {
+		timeout = defaultTimeouts[cmd]
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopTimeout := func() {}
+	if timeout > 0 {
+		ctx, stopTimeout = context.WithTimeout(ctx, timeout)
+	}
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-done:
+			return
+		case sig := <-signals:
+			lgr.Warn("Interrupted, stopping, interrupt again to exit right away", "Signal", sig)
+			cancel()
+		}
+		select {
+		case <-done:
+		case <-signals:
+			os.Exit(clierror.Interrupted.ExitCode())
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		stopTimeout()
+		cancel()
+	}
+}
+
+// newReporter shows the phases of a long command on stderr, with a spinner on a terminal,
+// there is no progress next to a json console log
+func newReporter() *progress.Reporter {
+	return progress.New(&progress.Options{
+		Console:     console,
+		Interactive: isatty.IsTerminal(os.Stderr.Fd()),
+		Disabled:    logFormat == logging.FormatJSON,
+	})
+}
+
+// newHookRunner runs the hooks of the config file, their output goes to the console
+func newHookRunner() (*hooks.Runner, error) {
+	config := hooks.Config{}
+	if err := viper.UnmarshalKey("hooks", &config); err != nil {
+		return nil, clierror.Errorf(clierror.Config, "Invalid hooks in %s: %v", viper.ConfigFileUsed(), err)
+	}
+	return hooks.New(&hooks.Options{
+		Config: config,
+		Output: console,
+		Logger: lgr,
+	})
+}
+
+// waitFor calls ready every interval until it returns true, it fails with a clierror.Timeout error
+// after timeout and with the error of ctx once ctx is done
+func waitFor(ctx context.Context, what string, timeout time.Duration, interval time.Duration, ready func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := ready()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return clierror.Errorf(clierror.Timeout, "%s did not happen in %s", what, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return clierror.FromContext(ctx, "Waiting for "+what)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// printResult writes obj to stdout in the format selected with --output
+func printResult(obj interface{}) error {
+	p, err := printer.New(outputFormat, os.Stdout)
+	if err != nil {
+		return err
+	}
+	return p.Print(obj)
 }
 
 func getKubeClientBuilder(context string, namespace string, path string, inCluster bool) kube.Kube {
@@ -170,12 +389,61 @@ func getKubeClientBuilder(context string, namespace string, path string, inClust
 	})
 }
 
-func createLogger(command string, verbose bool) logger.Logger {
-	logFile := "venonalog.json"
-	os.Remove(logFile)
-	return logger.New(&logger.Options{
-		Command:   command,
-		Verbose:   verbose,
-		LogToFile: logFile,
-	})
+func createLogger(command string) (logger.Logger, error) {
+	opt := &logging.Options{
+		Command:  command,
+		Level:    logLevel,
+		Format:   logFormat,
+		Output:   console, // stdout is kept for the command output
+		File:     logFile,
+		MaxFiles: logMaxFiles,
+		MaxAge:   logMaxAge,
+	}
+	// the completions run on every tab, they would replace the run logs of the commands
+	if logFile == "" && !isCompletionCommand(command) {
+		dir, err := sharoncliDir()
+		if err != nil {
+			return nil, err
+		}
+		opt.Dir = path.Join(dir, "logs")
+	}
+	return logging.New(opt)
+}
+
+// sharoncliDir returns the directory that holds the state of sharoncli ($HOME/.sharoncli)
+func sharoncliDir() (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(home, ".sharoncli"), nil
+}
+
+// loadState reads the state kept in $HOME/.sharoncli/state.json
+func loadState() (*state.State, error) {
+	dir, err := sharoncliDir()
+	if err != nil {
+		return nil, err
+	}
+	return state.Load(path.Join(dir, "state.json"))
+}
+
+// commandName returns the command path without the binary name, e.g. "create runtime"
+func commandName(cmd *cobra.Command) string {
+	return strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+}
+
+// usageArgs makes the errors of the argument validators of the commands usage errors, like the errors of the flags
+func usageArgs(c *cobra.Command) {
+	if args := c.Args; args != nil {
+		c.Args = func(cmd *cobra.Command, a []string) error {
+			if err := args(cmd, a); err != nil {
+				return clierror.New(clierror.Usage, err)
+			}
+			return nil
+		}
+	}
+	for _, child := range c.Commands() {
+		usageArgs(child)
+	}
 }
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/inconshreveable/log15"
+)
+
+// TestAPITransportScope replays the requests to the Codefresh API host and sends the others to the network
+func TestAPITransportScope(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "from the network")
+	}))
+	defer server.Close()
+	defer func(transport http.RoundTripper, host string) {
+		http.DefaultTransport = transport
+		cfAPIHost = host
+		replayAPIDir = ""
+	}(http.DefaultTransport, cfAPIHost)
+	l := log.New()
+	l.SetHandler(log.DiscardHandler())
+	lgr = l
+	replayAPIDir = "testdata/venona"
+	cfAPIHost = "https://g.codefresh.io/"
+	if err := setupAPITransport(); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(method string, url string) (string, error) {
+		req, _ := http.NewRequest(method, url, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		return string(data), err
+	}
+	if body, err := get("GET", server.URL+"/api/custom_clusters/validate"); err != nil || body != "from the network" {
+		t.Errorf("expected the request to another host to reach it, got %q, %v", body, err)
+	}
+	if _, err := get("POST", "https://g.codefresh.io/api/custom_clusters/validate"); err != nil {
+		t.Errorf("expected the recording of the Codefresh API request, got %v", err)
+	}
+	if _, err := get("GET", "https://g.codefresh.io/api/runtime-environments"); err == nil || !strings.Contains(err.Error(), "No recording for GET /api/runtime-environments") {
+		t.Errorf("expected no recording error, got %v", err)
+	}
+}
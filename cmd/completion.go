@@ -0,0 +1,216 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codefresh-io/venona/venonactl/pkg/store"
+	"github.com/sharon-vendrov/sharoncli/pkg/config"
+	"github.com/sharon-vendrov/sharoncli/pkg/logic"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// completionCacheTTL is how long the names read from the Codefresh API complete flags before they are read again
+const completionCacheTTL = 2 * time.Minute
+
+// completionCmd represents the completion command
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish",
+	Short: "Print the shell completion script",
+	Long: `Print the shell completion script, the flags complete pipeline names, runtime environments,
+kube contexts and cloud providers.
+
+  bash: source <(sharoncli completion bash)
+  zsh:  sharoncli completion zsh > "${fpath[1]}/_sharoncli"
+  fish: sharoncli completion fish > ~/.config/fish/completions/sharoncli.fish`,
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Args:      cobra.ExactValidArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletion(os.Stdout)
+		case "zsh":
+			return rootCmd.GenZshCompletion(os.Stdout)
+		default:
+			return rootCmd.GenFishCompletion(os.Stdout, true)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+// isCompletionCommand is true for the completion command and the hidden command the completion scripts run
+func isCompletionCommand(command string) bool {
+	return command == "completion" || command == cobra.ShellCompRequestCmd || command == cobra.ShellCompNoDescRequestCmd
+}
+
+// completePipelineNames completes with the full names of the pipelines of the Codefresh account
+func completePipelineNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeCached("pipelines", toComplete, func() ([]string, error) {
+		s, err := newService()
+		if err != nil {
+			return nil, err
+		}
+		pipelines, err := s.ListPipelines(cmdCtx, &logic.ListPipelinesOptions{})
+		if err != nil {
+			return nil, err
+		}
+		names := []string{}
+		for _, p := range pipelines {
+			names = append(names, p.Metadata.Name)
+		}
+		return names, nil
+	})
+}
+
+// completePipelineArg completes the pipeline name of the commands that take one argument
+func completePipelineArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completePipelineNames(cmd, args, toComplete)
+}
+
+// completeRuntimeEnvironments completes with the runtime environments of the Codefresh account
+func completeRuntimeEnvironments(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeCached("runtime-environments", toComplete, func() ([]string, error) {
+		if err := extendStoreWithCodefershClient(lgr); err != nil {
+			return nil, err
+		}
+		res, err := store.GetStore().CodefreshAPI.Client.RuntimeEnvironments().List()
+		if err != nil {
+			return nil, err
+		}
+		names := []string{}
+		for _, re := range res {
+			names = append(names, re.Metadata.Name)
+		}
+		return names, nil
+	})
+}
+
+// completeKubeContexts completes with the contexts of the kubeconfig, like kubectl --kube-config-path
+// or $KUBECONFIG and then $HOME/.kube/config
+func completeKubeContexts(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeConfigPath
+	config, err := rules.Load()
+	if err != nil {
+		lgr.Debug("Failed to read kubeconfig", "Error", err)
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names := []string{}
+	for name := range config.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return filterPrefix(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeCloudProviders completes with the providers create runtime supports, with their description
+func completeCloudProviders(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names := []string{}
+	for _, p := range cloudProviders {
+		if strings.HasPrefix(p.name, toComplete) {
+			names = append(names, p.name+"\t"+p.description)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProfiles completes with the profiles of the config file
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	file, err := readConfigFile()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return filterPrefix(config.Profiles(file), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeCached completes with the names of list, they are kept in $HOME/.sharoncli/cache for
+// completionCacheTTL by Codefresh host so that every tab does not call the API
+func completeCached(kind string, toComplete string, list func() ([]string, error)) ([]string, cobra.ShellCompDirective) {
+	file := completionCacheFile(kind)
+	if names, ok := readCompletionCache(file); ok {
+		return filterPrefix(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+	names, err := list()
+	if err != nil {
+		lgr.Debug("Failed to get the completions", "Kind", kind, "Error", err)
+		return nil, cobra.ShellCompDirectiveError
+	}
+	if file != "" {
+		if err := writeCompletionCache(file, names); err != nil {
+			lgr.Debug("Failed to cache the completions", "File", file, "Error", err)
+		}
+	}
+	return filterPrefix(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completionCacheFile is the cache of kind for the Codefresh host of the current context, empty when
+// there is no context
+func completionCacheFile(kind string) string {
+	if err := extendStoreWithCodefershClient(lgr); err != nil {
+		return ""
+	}
+	dir, err := sharoncliDir()
+	if err != nil {
+		return ""
+	}
+	host := sha256.Sum256([]byte(store.GetStore().CodefreshAPI.Host))
+	return path.Join(dir, "cache", fmt.Sprintf("%s-%x.json", kind, host[:6]))
+}
+
+func readCompletionCache(file string) ([]string, bool) {
+	if file == "" {
+		return nil, false
+	}
+	info, err := os.Stat(file)
+	if err != nil || time.Since(info.ModTime()) > completionCacheTTL {
+		return nil, false
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	names := []string{}
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, false
+	}
+	return names, true
+}
+
+func writeCompletionCache(file string, names []string) error {
+	data, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+func filterPrefix(names []string, prefix string) []string {
+	matches := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+	return matches
+}
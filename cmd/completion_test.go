@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/mockapi"
+)
+
+// complete runs the command the completion scripts run and returns the completions and the directive
+func complete(t *testing.T, args ...string) ([]string, string) {
+	out := &bytes.Buffer{}
+	rootCmd.SetOut(out)
+	rootCmd.SetErr(ioutil.Discard)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetErr(nil)
+	if err := execute(append([]string{"__complete"}, args...)); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	last := len(lines) - 1
+	if last < 0 || !strings.HasPrefix(lines[last], ":") {
+		t.Fatalf("no directive in the output %q", out.String())
+	}
+	return lines[:last], lines[last]
+}
+
+func TestCompletion(t *testing.T) {
+	home, restore := useHome(t)
+	defer restore()
+	defer func(path string, kubeConfig string) {
+		configPath = path
+		kubeConfigPath = kubeConfig
+		cfAPIHost = ""
+		cfAPIToken = ""
+	}(configPath, kubeConfigPath)
+
+	server := httptest.NewServer(mockapi.New(&mockapi.Options{Scenario: &mockapi.Scenario{
+		Pipelines: []map[string]interface{}{
+			{"metadata": map[string]interface{}{"name": "demo/hello"}},
+			{"metadata": map[string]interface{}{"name": "demo/build"}},
+			{"metadata": map[string]interface{}{"name": "other/hello"}},
+		},
+		RuntimeEnvironments: []*mockapi.RuntimeEnvironment{{Name: "kind/codefresh"}},
+	}}))
+	defer server.Close()
+	cfConfig, err := writeMockConfig("mock", server.URL, mockapi.DefaultToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(cfConfig)
+
+	noFiles := ":4"
+	tests := []struct {
+		name        string
+		args        []string
+		completions []string
+	}{
+		{"cloud providers", []string{"create", "runtime", "--cloud-provider", "on"}, []string{"on-prem\tkind cluster on the local docker daemon"}},
+		{"kube contexts", []string{"create", "runtime", "--kube-config-path", "testdata/kubeconfig", "--kube-context-name", ""}, []string{"kubernetes-admin@kind"}},
+		{"pipeline names", []string{"test", "runtime", "--cfconfig", cfConfig, "--name", "demo/"}, []string{"demo/build", "demo/hello"}},
+		{"pipeline names from the cache", []string{"test", "runtime", "--cfconfig", cfConfig, "--name", "o"}, []string{"other/hello"}},
+		{"pipeline argument", []string{"pipelines", "get", "--cfconfig", cfConfig, "other/"}, []string{"other/hello"}},
+		{"one pipeline argument", []string{"pipelines", "export", "--cfconfig", cfConfig, "demo/hello", ""}, []string{}},
+		{"runtime environments", []string{"test", "runtime", "--cfconfig", cfConfig, "--runtime-environment", ""}, []string{"kind/codefresh"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			completions, directive := complete(t, test.args...)
+			if directive != noFiles {
+				t.Errorf("expected the directive %s, got %s", noFiles, directive)
+			}
+			if !reflect.DeepEqual(completions, test.completions) {
+				t.Errorf("expected %q, got %q", test.completions, completions)
+			}
+		})
+	}
+	if cached, _ := filepath.Glob(filepath.Join(home, ".sharoncli", "cache", "pipelines-*.json")); len(cached) != 1 {
+		t.Errorf("expected the pipelines to be cached, got %v", cached)
+	}
+}
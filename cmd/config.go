@@ -0,0 +1,559 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"github.com/sharon-vendrov/sharoncli/pkg/config"
+	"github.com/sharon-vendrov/sharoncli/pkg/hooks"
+	"github.com/sharon-vendrov/sharoncli/pkg/logging"
+	"github.com/sharon-vendrov/sharoncli/pkg/printer"
+	"github.com/sharon-vendrov/sharoncli/pkg/report"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	// profile selects a profile of the config file
+	profile string
+
+	configInitForce bool
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the config file",
+	Long: `Manage the config file ($HOME/.sharoncli.yaml). Its keys set the flags the command line does not set:
+a key is a global flag, a map is the flags of a command like create-runtime, and profiles.<name> has the same
+keys, on top of the file when selected with --profile.
+
+Every command checks the keys it reads: the global flags and its own section, in the file and in the selected
+profile. It fails with exit code 3 when a value is not one its flag accepts or when the profile is unknown.
+Unknown keys and the problems of the other keys are warnings, config validate lists them. The commands that
+run hooks fail on an invalid hook. The completion, config and plugin commands do not read the config file`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return clierror.Errorf(clierror.Usage, "Provide item to the config command")
+	},
+}
+
+// configViewCmd represents the config view command
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Print the flag values of the config file",
+	Long:  `Print the flag values the config file sets, with --profile the values of the profile merged on top of the values of the file`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := readConfigFile()
+		if err != nil {
+			return err
+		}
+		settings, err := config.Load(file, selectedProfile(cmd, file))
+		if err != nil {
+			return clierror.New(clierror.Config, err)
+		}
+		return printResult(settings.Map())
+	},
+}
+
+// configGetCmd represents the config get command
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a value of the config file",
+	Long: `Print the value of a key of the config file like output, create-runtime.kube-namespace or hooks,
+with --profile the value of the profile when it sets the key`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := readConfigFile()
+		if err != nil {
+			return err
+		}
+		settings, err := config.Load(file, selectedProfile(cmd, file))
+		if err != nil {
+			return clierror.New(clierror.Config, err)
+		}
+		if value, ok := config.Get(settings.Map(), config.Path(args[0])); ok {
+			return printResult(value)
+		}
+		if value, ok := config.Get(file, config.Path(args[0])); ok {
+			return printResult(value)
+		}
+		return clierror.Errorf(clierror.Config, "%s is not set in %s", args[0], configFilePath())
+	},
+}
+
+// configSetCmd represents the config set command
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>...",
+	Short: "Set a flag in the config file",
+	Long: `Set a flag in the config file, a global flag like output or the flag of a command like create-runtime.kube-namespace,
+in the profile with --profile. A flag that can be repeated takes every value. The value is checked against the type of the
+flag and the values the command accepts, like the formats of output, and the file is rewritten without its comments`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := profilePath(cmd, args[0])
+		key := strings.Join(path, ".")
+		if path[0] == config.HooksKey {
+			return clierror.Errorf(clierror.Config, "The hooks are lists, edit %s to change them", configFilePath())
+		}
+		file, err := readConfigFile()
+		if err != nil {
+			return err
+		}
+		schema := configSchema(cmd.Root())
+		typ := schema.FlagType(path)
+		if key == config.ProfileKey {
+			typ = "string"
+		}
+		var value interface{} = strings.Join(args[1:], " ")
+		if typ != "" {
+			if value, err = config.ParseValue(typ, args[1:]); err != nil {
+				return clierror.Errorf(clierror.Config, "Invalid value of %s: %v", key, err)
+			}
+		}
+		if err := config.Set(file, path, value); err != nil {
+			return clierror.Errorf(clierror.Config, "Failed to set %s: %v", key, err)
+		}
+		if problems := validateConfig(schema, file).For(key); len(problems) > 0 {
+			return clierror.Errorf(clierror.Config, "Invalid %s: %s", problems[0].Key, problems[0].Message)
+		}
+		if err := writeConfigFile(file); err != nil {
+			return err
+		}
+		lgr.Info("Set flag in config file", "Key", key, "File", configFilePath())
+		return nil
+	},
+}
+
+// configUnsetCmd represents the config unset command
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a key from the config file",
+	Long:  `Remove a key from the config file, from the profile with --profile. The file is rewritten without its comments`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := profilePath(cmd, args[0])
+		key := strings.Join(path, ".")
+		file, err := readConfigFile()
+		if err != nil {
+			return err
+		}
+		if !config.Unset(file, path) {
+			return clierror.Errorf(clierror.Config, "%s is not set in %s", key, configFilePath())
+		}
+		if err := writeConfigFile(file); err != nil {
+			return err
+		}
+		lgr.Info("Removed key from config file", "Key", key, "File", configFilePath())
+		return nil
+	},
+}
+
+// configValidateCmd represents the config validate command
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the keys and values of the config file",
+	Long: `Check that every key of the config file is a global flag, a command or a flag of the command with a value of
+the type of the flag, that the profiles have the same keys and that the hooks have known events and a command.
+The problems are printed and the command exits with exit code 3`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := os.Stat(configFilePath()); os.IsNotExist(err) {
+			lgr.Info("There is no config file, sharoncli config init creates one", "File", configFilePath())
+			return nil
+		}
+		file, err := readConfigFile()
+		if err != nil {
+			return err
+		}
+		problems := validateConfig(configSchema(cmd.Root()), file)
+		if len(problems) == 0 {
+			lgr.Info("The config file is valid", "File", configFilePath())
+			return nil
+		}
+		if err := printResult(problems); err != nil {
+			return err
+		}
+		return clierror.Errorf(clierror.Config, "Found %d problems in config file %s", len(problems), configFilePath())
+	},
+}
+
+// configInitCmd represents the config init command
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a config file",
+	Long: `Create a config file with the answers to a few questions, an empty answer takes the default in brackets.
+Without a terminal the answers are read from stdin`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := configFilePath()
+		if _, err := os.Stat(path); err == nil && !configInitForce {
+			return clierror.Errorf(clierror.Config, "Config file %s exists, use --force to replace it", path)
+		}
+		file, err := askConfig(bufio.NewReader(os.Stdin), os.Stderr, configSchema(cmd.Root()))
+		if err != nil {
+			return err
+		}
+		if err := writeConfigFile(file); err != nil {
+			return err
+		}
+		lgr.Info("Created config file", "File", path)
+		return nil
+	},
+}
+
+func init() {
+	configInitCmd.Flags().BoolVar(&configInitForce, "force", false, "Replace the config file when it exists")
+
+	configCmd.AddCommand(configViewCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configInitCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// configFilePath is the config file initConfig found or --config, $HOME/.sharoncli.yaml when there is none
+func configFilePath() string {
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return ".sharoncli.yaml"
+	}
+	return filepath.Join(home, ".sharoncli.yaml")
+}
+
+// readConfigFile returns the keys of the config file, empty when there is none. Yaml and json files keep
+// the case of their keys, viper reads the other formats and lower-cases their keys
+func readConfigFile() (map[string]interface{}, error) {
+	path := configFilePath()
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]interface{}{}, nil
+	}
+	if err != nil {
+		return nil, clierror.Errorf(clierror.Config, "Failed to read config file %s: %v", path, err)
+	}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml", ".json", "":
+		file, err := config.Parse(data)
+		if err != nil {
+			return nil, clierror.Errorf(clierror.Config, "Failed to read config file %s: %v", path, err)
+		}
+		return file, nil
+	}
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, clierror.Errorf(clierror.Config, "Failed to read config file %s: %v", path, err)
+	}
+	return v.AllSettings(), nil
+}
+
+// writeConfigFile writes the keys to the config file, as json when it is a .json file and as yaml otherwise
+func writeConfigFile(file map[string]interface{}) error {
+	path := configFilePath()
+	var data []byte
+	var err error
+	switch filepath.Ext(path) {
+	case ".json":
+		if data, err = json.MarshalIndent(file, "", "  "); err == nil {
+			data = append(data, '\n')
+		}
+	case ".yaml", ".yml", "":
+		data, err = yaml.Marshal(file)
+	default:
+		return clierror.Errorf(clierror.Config, "Only yaml and json config files can be changed, edit %s", path)
+	}
+	if err != nil {
+		return clierror.Errorf(clierror.Config, "Failed to write config file %s: %v", path, err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return clierror.Errorf(clierror.Config, "Failed to write config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// profilePath is the path of the key in the profile of --profile, the path of the key itself without the flag
+func profilePath(cmd *cobra.Command, key string) []string {
+	if flag := cmd.Flags().Lookup("profile"); flag != nil && flag.Changed {
+		return config.ProfilePath(profile, key)
+	}
+	return config.Path(key)
+}
+
+// selectedProfile is --profile, or the profile key of the config file
+func selectedProfile(cmd *cobra.Command, file map[string]interface{}) string {
+	if flag := cmd.Flags().Lookup("profile"); flag != nil && flag.Changed {
+		return profile
+	}
+	if name, ok := file[config.ProfileKey].(string); ok {
+		return name
+	}
+	return profile
+}
+
+// configSchema is the flags the config file can set: the persistent flags of root and, by command section,
+// the flags of every command that applies the config file
+func configSchema(root *cobra.Command) *config.Schema {
+	schema := config.NewSchema()
+	root.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if !isConfigFileFlag(f.Name) {
+			schema.Global[f.Name] = f.Value.Type()
+		}
+	})
+	var add func(c *cobra.Command)
+	add = func(c *cobra.Command) {
+		for _, child := range c.Commands() {
+			if child == configCmd || child.Hidden || isPluginCommand(child) || isCompletionCommand(child.Name()) || child.Name() == "help" {
+				continue
+			}
+			if !child.HasSubCommands() {
+				flags := map[string]string{}
+				child.LocalFlags().VisitAll(func(f *pflag.Flag) {
+					if f.Name != "help" {
+						flags[f.Name] = f.Value.Type()
+					}
+				})
+				child.InheritedFlags().VisitAll(func(f *pflag.Flag) {
+					if !isConfigFileFlag(f.Name) {
+						flags[f.Name] = f.Value.Type()
+					}
+				})
+				schema.Commands[configSection(child)] = flags
+			}
+			add(child)
+		}
+	}
+	add(root)
+	for key, check := range configChecks {
+		schema.Checks[key] = check
+	}
+	return schema
+}
+
+// isConfigFileFlag is true for the flags the config file cannot set
+func isConfigFileFlag(name string) bool {
+	return name == "config" || name == "profile" || name == "help"
+}
+
+// validateConfig returns the problems of the keys of the config file and of its hooks
+func validateConfig(schema *config.Schema, file map[string]interface{}) config.Problems {
+	problems := schema.Validate(file)
+	if value, ok := file[config.HooksKey]; ok {
+		v := viper.New()
+		v.Set(config.HooksKey, value)
+		hooksConfig := hooks.Config{}
+		err := v.UnmarshalKey(config.HooksKey, &hooksConfig)
+		if err == nil {
+			_, err = hooks.New(&hooks.Options{Config: hooksConfig})
+		}
+		if err != nil {
+			problems = append(problems, &config.Problem{Key: config.HooksKey, Message: err.Error()})
+		}
+	}
+	return problems
+}
+
+// applyConfig sets the flags that were not set on the command line to the values of the config file
+// and of the selected profile. It fails when a flag the command reads has an invalid value and returns the
+// problems of the other keys and the unknown keys, only config validate fails on them. The completion, config
+// and plugin commands do not read the config file
+func applyConfig(cmd *cobra.Command) (config.Problems, error) {
+	if cmd == configCmd || cmd.Parent() == configCmd || isCompletionCommand(cmd.Name()) || isPluginCommand(cmd) {
+		return nil, nil
+	}
+	file, err := readConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	section, name := configSection(cmd), selectedProfile(cmd, file)
+	problems, ignored := config.Problems{}, config.Problems{}
+	for _, problem := range validateConfig(configSchema(cmd.Root()), file) {
+		if !problem.Unknown && config.Applies(file, problem.Key, section, name) {
+			problems = append(problems, problem)
+		} else {
+			ignored = append(ignored, problem)
+		}
+	}
+	if len(problems) > 0 {
+		more := ""
+		if len(problems) > 1 {
+			more = fmt.Sprintf(" (and %d more problems, see sharoncli config validate)", len(problems)-1)
+		}
+		return nil, clierror.Errorf(clierror.Config, "Invalid config file %s: %s: %s%s", configFilePath(), problems[0].Key, problems[0].Message, more)
+	}
+	settings, err := config.Load(file, name)
+	if err != nil {
+		return nil, clierror.New(clierror.Config, err)
+	}
+	if err := setFlags(cmd.Root().PersistentFlags(), settings.Global, ""); err != nil {
+		return nil, err
+	}
+	return ignored, setFlags(cmd.Flags(), settings.Commands[section], section+".")
+}
+
+// configSection is the key of the flags of the command in the config file, create runtime is create-runtime
+func configSection(cmd *cobra.Command) string {
+	return strings.Replace(commandName(cmd), " ", "-", -1)
+}
+
+func setFlags(flags *pflag.FlagSet, values map[string]interface{}, prefix string) error {
+	for name, value := range values {
+		// the unknown keys are ignored with a warning
+		flag := flags.Lookup(name)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		values, err := config.Values(value)
+		if err != nil {
+			return clierror.Errorf(clierror.Config, "Invalid value of %s%s in config file %s: %v", prefix, name, configFilePath(), err)
+		}
+		for _, v := range values {
+			if err := flags.Set(name, v); err != nil {
+				return clierror.Errorf(clierror.Config, "Invalid value of %s%s in config file %s: %v", prefix, name, configFilePath(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// configChecks validate the values of the config file like the commands validate their flags
+var configChecks = map[string]func(value string) error{
+	"output": func(value string) error {
+		_, err := printer.New(value, ioutil.Discard)
+		return err
+	},
+	"log-format": func(value string) error {
+		if value != logging.FormatText && value != logging.FormatJSON {
+			return fmt.Errorf("Unknown log format %q, supported formats are %s and %s", value, logging.FormatText, logging.FormatJSON)
+		}
+		return nil
+	},
+	"api-retries": func(value string) error {
+		if strings.HasPrefix(value, "-") {
+			return fmt.Errorf("api-retries cannot be negative")
+		}
+		return nil
+	},
+	"create-runtime.cloud-provider": func(value string) error {
+		names := []string{}
+		for _, p := range cloudProviders {
+			names = append(names, p.name)
+		}
+		if !contains(names, value) {
+			return fmt.Errorf("The cloud-provider isn't supported, the cloud providers are %s", strings.Join(names, ", "))
+		}
+		return nil
+	},
+	"test-runtime.report": func(value string) error {
+		_, err := report.Parse(value)
+		return err
+	},
+}
+
+// configQuestions are the flags config init asks for, with the values they accept when they are a choice
+var configQuestions = []struct {
+	key      string
+	question string
+	defaults string
+	choices  func() []string
+}{
+	{key: "codefresh-context", question: "Context of the codefresh config file, empty for its current context"},
+	{key: "output", question: "Output format", defaults: printer.FormatTable, choices: func() []string {
+		return []string{printer.FormatTable, printer.FormatWide, printer.FormatJSON, printer.FormatYAML}
+	}},
+	{key: "create-runtime.cloud-provider", question: "Cloud provider of create runtime", defaults: "on-prem", choices: func() []string {
+		names := []string{}
+		for _, p := range cloudProviders {
+			names = append(names, p.name)
+		}
+		return names
+	}},
+	{key: "create-runtime.kube-namespace", question: "Namespace of the runtime", defaults: "codefresh"},
+	{key: "test-runtime.name", question: "Pipelines of test runtime separated by commas, empty for the smoke test pipeline"},
+}
+
+// askConfig asks the configQuestions on out and returns the keys of the config file of the answers,
+// an answer that is not valid is asked again and the end of in takes the defaults
+func askConfig(in *bufio.Reader, out io.Writer, schema *config.Schema) (map[string]interface{}, error) {
+	file := map[string]interface{}{}
+	eof := false
+	for _, q := range configQuestions {
+		for {
+			prompt := q.question
+			if q.choices != nil {
+				prompt += " (" + strings.Join(q.choices(), "|") + ")"
+			}
+			if q.defaults != "" {
+				prompt += " [" + q.defaults + "]"
+			}
+			fmt.Fprintf(out, "%s: ", prompt)
+			answer := ""
+			if !eof {
+				line, err := in.ReadString('\n')
+				if err == io.EOF {
+					eof = true
+					fmt.Fprintln(out)
+				} else if err != nil {
+					return nil, fmt.Errorf("Failed to read the answer: %v", err)
+				}
+				answer = strings.TrimSpace(line)
+			}
+			if answer == "" {
+				answer = q.defaults
+			}
+			if answer == "" {
+				break
+			}
+			if q.choices != nil && !contains(q.choices(), answer) {
+				fmt.Fprintf(out, "%s is not one of %s\n", answer, strings.Join(q.choices(), ", "))
+				continue
+			}
+			args := []string{answer}
+			typ := schema.FlagType(config.Path(q.key))
+			if strings.HasSuffix(typ, "Array") || strings.HasSuffix(typ, "Slice") {
+				args = strings.Split(answer, ",")
+				for i := range args {
+					args[i] = strings.TrimSpace(args[i])
+				}
+			}
+			value, err := config.ParseValue(typ, args)
+			if err != nil {
+				fmt.Fprintf(out, "%v\n", err)
+				continue
+			}
+			if err := config.Set(file, config.Path(q.key), value); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return file, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
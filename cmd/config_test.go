@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"github.com/sharon-vendrov/sharoncli/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// useConfigFile makes the content the config file of the commands, the returned func restores the previous
+// one and the global flags it set
+func useConfigFile(t *testing.T, content string) func() {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, ".sharoncli.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	previous := viper.ConfigFileUsed()
+	viper.SetConfigFile(path)
+	flags := rootCmd.PersistentFlags()
+	values := map[string]string{}
+	flags.VisitAll(func(f *pflag.Flag) {
+		values[f.Name] = f.Value.String()
+	})
+	return func() {
+		flags.VisitAll(func(f *pflag.Flag) {
+			if f.Changed {
+				f.Value.Set(values[f.Name])
+				f.Changed = false
+			}
+		})
+		viper.SetConfigFile(previous)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestApplyConfigScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		cmd     *cobra.Command
+		ignored int
+		kind    clierror.Kind
+	}{
+		{"section of another command", "create-runtime:\n  wait: soon\n", pluginListCmd, 1, clierror.Unknown},
+		{"section of the command", "create-runtime:\n  wait: soon\n", runtimeCmd, 0, clierror.Config},
+		{"profile that is not selected", "profiles:\n  ci:\n    output: [json]\n", pluginListCmd, 1, clierror.Unknown},
+		{"selected profile", "profile: ci\nprofiles:\n  ci:\n    output: [json]\n", pluginListCmd, 0, clierror.Config},
+		{"selected profile with capitals", "profile: CI\nprofiles:\n  CI:\n    output: [json]\n  ci: {}\n", pluginListCmd, 0, clierror.Config},
+		{"profile with another case", "profile: ci\nprofiles:\n  CI:\n    output: [json]\n  ci: {}\n", pluginListCmd, 1, clierror.Unknown},
+		{"selected profile with a dot", "profile: ci.v2\nprofiles:\n  ci:\n    v2: [json]\n  ci.v2:\n    output: [json]\n", pluginListCmd, 0, clierror.Config},
+		{"value the command does not accept", "profile: ci\nprofiles:\n  ci:\n    output: xml\n", pluginListCmd, 0, clierror.Config},
+		{"invalid global flag", "timeout: soon\n", pluginListCmd, 0, clierror.Config},
+		{"unknown global flag", "timeuot: 10m\n", pluginListCmd, 1, clierror.Unknown},
+		{"unknown flag of the command", "create-runtime:\n  wiat: 10s\n", runtimeCmd, 1, clierror.Unknown},
+		{"unknown flag of the selected profile", "profile: ci\nprofiles:\n  ci:\n    create-runtime:\n      wiat: 10s\n", runtimeCmd, 1, clierror.Unknown},
+		{"hooks", "hooks:\n  post-instal: []\n", pluginListCmd, 1, clierror.Unknown},
+		{"completion", "timeout: soon\n", completionCmd, 0, clierror.Unknown},
+		{"config", "timeout: soon\n", configValidateCmd, 0, clierror.Unknown},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer useConfigFile(t, test.file)()
+			ignored, err := applyConfig(test.cmd)
+			if len(ignored) != test.ignored || clierror.KindOf(err) != test.kind || (test.kind == clierror.Unknown && err != nil) {
+				t.Errorf("expected %d ignored problems and a %v error, got %v and %v", test.ignored, test.kind, ignored.Rows(false), err)
+			}
+		})
+	}
+}
+
+func TestConfigProfileNames(t *testing.T) {
+	_, restore := useHome(t)
+	defer restore()
+	defer useConfigFile(t, "profiles:\n  CI:\n    test-runtime:\n      name: [demo/hello]\n  ci:\n    output: json\n")()
+
+	if err := execute([]string{"config", "set", "--config", viper.ConfigFileUsed(), "--profile", "ci.v2", "output", "yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	file, err := readConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string]interface{}{"CI": nil, "ci": "json", "ci.v2": "yaml"} {
+		if settings, err := config.Load(file, name); err != nil || settings.Global["output"] != expected {
+			t.Errorf("profile %s: expected the output %v, got %v, %v", name, expected, settings, err)
+		}
+	}
+	if value, ok := config.Get(file, config.ProfilePath("CI", "test-runtime.name")); !ok || len(value.([]interface{})) != 1 {
+		t.Errorf("expected the test-runtime names of the CI profile, got %v", value)
+	}
+}
+
+func TestConfigInvalidValue(t *testing.T) {
+	_, restore := useHome(t)
+	defer restore()
+	defer useConfigFile(t, "profiles:\n  ci:\n    output: xml\n")()
+	path := viper.ConfigFileUsed()
+
+	for _, args := range [][]string{
+		{"config", "set", "--config", path, "output", "xml"},
+		{"config", "set", "--config", path, "create-runtime.cloud-provider", "gke"},
+		{"--config", path, "--profile", "ci", "plugin", "list"},
+	} {
+		if err := execute(args); clierror.KindOf(err) != clierror.Config {
+			t.Errorf("%v: expected a config error, got %v", args, err)
+		}
+	}
+}
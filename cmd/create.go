@@ -5,8 +5,7 @@ Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
 package cmd
 
 import (
-	"errors"
-
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
 	"github.com/spf13/cobra"
 )
 
@@ -16,7 +15,7 @@ var createCmd = &cobra.Command{
 	Short: "TODO",
 	Long:  `TODO`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return errors.New("Provide item to the create command")
+		return clierror.Errorf(clierror.Usage, "Provide item to the create command")
 	},
 }
 
@@ -5,13 +5,18 @@ Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"github.com/sharon-vendrov/sharoncli/pkg/hooks"
+	"github.com/sharon-vendrov/sharoncli/pkg/progress"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"sigs.k8s.io/kind/pkg/cluster"
 	"sigs.k8s.io/kind/pkg/cluster/create"
@@ -46,6 +51,15 @@ type venonaInstallCmdOptions struct {
 	skipRuntimeInstallation       bool
 	runtimeEnvironmentName        string
 	kubernetesRunnerType          bool
+	agentWait                     time.Duration
+}
+
+// cloudProviders are the values of --cloud-provider
+var cloudProviders = []struct {
+	name        string
+	description string
+}{
+	{"on-prem", "kind cluster on the local docker daemon"},
 }
 
 var flags = &flagpole{}
@@ -87,50 +101,173 @@ func init() {
 	runtimeCmd.Flags().BoolVar(&installCmdOptions.installOnlyRuntimeEnvironment, "only-runtime-environment", false, "Set to true to onlky configure namespace as runtime-environment for Codefresh")
 	runtimeCmd.Flags().BoolVar(&installCmdOptions.dryRun, "dry-run", false, "Set to true to simulate installation")
 	runtimeCmd.Flags().BoolVar(&installCmdOptions.setDefaultRuntime, "set-default", false, "Mark the install runtime-environment as default one after installation")
+	runtimeCmd.Flags().DurationVar(&installCmdOptions.agentWait, "agent-wait", time.Duration(5)*time.Minute, "Wait for the agent to register and be ready after the installation, 0 to not wait, --timeout stops the wait earlier")
 	runtimeCmd.Flags().BoolVar(&installCmdOptions.kubernetesRunnerType, "kubernetes-runner-type", false, "Set the runner type to kubernetes (alpha feature)")
 
+	runtimeCmd.RegisterFlagCompletionFunc("cloud-provider", completeCloudProviders)
+	runtimeCmd.RegisterFlagCompletionFunc("kube-context-name", completeKubeContexts)
+	runtimeCmd.RegisterFlagCompletionFunc("runtime-environment", completeRuntimeEnvironments)
+
 	createCmd.AddCommand(runtimeCmd)
 }
 
-func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+func runE(flags *flagpole, cmd *cobra.Command, args []string) (err error) {
+	rep := newReporter()
 	switch flags.CloudProvider {
 	case "on-prem":
 
-		// Check if the cluster name already exists
-		known, err := cluster.IsKnown(flags.Name)
+		// Check if the cluster name already exists and that Codefresh can be used before creating it
+		phase := rep.Start("Preflight checks")
+		var known bool
+		known, err = cluster.IsKnown(flags.Name)
 		if err != nil {
-			return err
+			return phase.Done(clierror.New(clierror.Provisioning, err))
 		}
 		if known {
-			return errors.Errorf("a cluster with the name %q already exists", flags.Name)
+			return phase.Done(clierror.Errorf(clierror.Provisioning, "a cluster with the name %q already exists", flags.Name))
 		}
-
-		// create a cluster context and create the cluster
-		ctx := cluster.NewContext(flags.Name)
-		fmt.Printf("Creating cluster %q ...\n", flags.Name)
-		if err = ctx.Create(
-			create.WithConfigFile(flags.Config),
-			create.WithNodeImage(flags.ImageName),
-			create.Retain(flags.Retain),
-			create.WaitForReady(flags.Wait),
-		); err != nil {
-			if utilErrors, ok := err.(util.Errors); ok {
-				for _, problem := range utilErrors.Errors() {
-					log.Error(problem)
-				}
-				return errors.New("aborting due to invalid configuration")
-			}
-			return errors.Wrap(err, "failed to create cluster")
+		if err = extendStoreWithCodefershClient(lgr); err != nil {
+			return phase.Done(err)
 		}
+		var hookRunner *hooks.Runner
+		if hookRunner, err = newHookRunner(); err != nil {
+			return phase.Done(err)
+		}
+		phase.Done(nil)
+
 		installCmdOptions.kube.context = "kubernetes-admin@kind"
 		installCmdOptions.clusterNameInCodefresh = "kubernetes-admin@kind"
 		homePath := os.Getenv("HOME")
 		kubeConfigPath = homePath + "/.kube/kind-config-kind"
+		if err = hookRunner.Run(cmdCtx, hooks.PreCreate, hookRuntime()); err != nil {
+			return err
+		}
+
+		// create a cluster context and create the cluster, kind shows its own progress
+		kindCtx := cluster.NewContext(flags.Name)
+		phase = rep.Start(fmt.Sprintf("Create cluster %s", flags.Name))
+		phase.Pause()
+		if err = phase.Done(createCluster(cmdCtx, kindCtx, flags)); err != nil {
+			return err
+		}
+		// an interrupted or timed out installation removes the cluster, a failed one keeps it to debug the failure
+		defer func() {
+			if err == nil || cmdCtx.Err() == nil || flags.Retain {
+				return
+			}
+			// the command was interrupted, the hooks still get to run
+			if hookErr := hookRunner.Run(context.Background(), hooks.PreDelete, hookRuntime()); hookErr != nil {
+				lgr.Error("Keeping the cluster of the interrupted runtime", "Name", flags.Name, "Error", hookErr)
+				return
+			}
+			lgr.Info("Deleting the cluster of the interrupted runtime, use --retain to keep it", "Name", flags.Name)
+			if deleteErr := kindCtx.Delete(); deleteErr != nil {
+				lgr.Error("Failed to delete the cluster", "Name", flags.Name, "Error", deleteErr)
+			}
+		}()
+		if err := clierror.FromContext(cmdCtx, "Creating the runtime"); err != nil {
+			return err
+		}
+
+		if flags.Wait > 0 {
+			if err := waitForNodes(cmdCtx, rep, flags.Wait); err != nil {
+				return err
+			}
+		}
+		if err := hookRunner.Run(cmdCtx, hooks.PostCluster, hookRuntime()); err != nil {
+			return err
+		}
+		if err := installVenona(cmdCtx, rep, *installCmdOptions); err != nil {
+			return err
+		}
+		return hookRunner.Run(cmdCtx, hooks.PostInstall, hookRuntime())
 	default:
-		return errors.Errorf("The cloud-provider isn't supported")
+		return clierror.Errorf(clierror.Usage, "The cloud-provider isn't supported")
+	}
+}
+
+// hookRuntime describes the runtime that create runtime installs to the hooks, with the defaults of installVenona
+func hookRuntime() *hooks.Runtime {
+	namespace := installCmdOptions.kube.namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	runtimeEnvironment := fmt.Sprintf("%s/%s", installCmdOptions.clusterNameInCodefresh, namespace)
+	if installCmdOptions.skipRuntimeInstallation {
+		runtimeEnvironment = installCmdOptions.runtimeEnvironmentName
+	}
+	return &hooks.Runtime{
+		KubeConfig:         kubeConfigPath,
+		KubeContext:        installCmdOptions.kube.context,
+		Namespace:          namespace,
+		RuntimeEnvironment: runtimeEnvironment,
+	}
+}
+
+// waitForNodes waits for the nodes of the new cluster to be ready, like kind a timeout is only a warning
+func waitForNodes(ctx context.Context, rep *progress.Reporter, timeout time.Duration) error {
+	phase := rep.Start("Wait for the nodes to be ready")
+	cs, err := getKubeClientBuilder(installCmdOptions.kube.context, "", kubeConfigPath, false).BuildClient()
+	if err != nil {
+		phase.Warn(err.Error())
+		return nil
+	}
+	err = waitFor(ctx, "the nodes readiness", timeout, time.Second, func() (bool, error) {
+		nodes, err := cs.CoreV1().Nodes().List(metav1.ListOptions{})
+		if err != nil {
+			lgr.Debug("Failed to list the nodes", "Error", err)
+			return false, nil
+		}
+		for _, node := range nodes.Items {
+			if !isNodeReady(&node) {
+				return false, nil
+			}
+		}
+		return len(nodes.Items) > 0, nil
+	})
+	if !endWait(ctx, phase, err) {
+		return err
 	}
+	return nil
+}
 
-	installVenona(*installCmdOptions)
+func isNodeReady(node *corev1.Node) bool {
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
 
+// createCluster creates the kind cluster, kind cannot be stopped halfway so when ctx is done it waits
+// for kind to finish and returns, the caller removes the cluster
+func createCluster(ctx context.Context, kindCtx *cluster.Context, flags *flagpole) error {
+	created := make(chan error, 1)
+	go func() {
+		created <- kindCtx.Create(
+			create.WithConfigFile(flags.Config),
+			create.WithNodeImage(flags.ImageName),
+			create.Retain(flags.Retain),
+			// the nodes are waited for by create runtime, with its own progress
+			create.WaitForReady(0),
+		)
+	}()
+	var err error
+	select {
+	case err = <-created:
+	case <-ctx.Done():
+		lgr.Warn("Waiting for kind to finish creating the cluster before removing it")
+		err = <-created
+	}
+	if err != nil {
+		if utilErrors, ok := err.(util.Errors); ok {
+			for _, problem := range utilErrors.Errors() {
+				lgr.Error(problem.Error())
+			}
+			return clierror.Errorf(clierror.Config, "aborting due to invalid configuration")
+		}
+		return clierror.New(clierror.Provisioning, errors.Wrap(err, "failed to create cluster"))
+	}
 	return nil
 }
@@ -0,0 +1,24 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"github.com/spf13/cobra"
+)
+
+// devCmd represents the dev command
+var devCmd = &cobra.Command{
+	Use:   "dev",
+	Short: "Tools for developing and demoing sharoncli",
+	Long:  `Tools for developing and demoing sharoncli`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return clierror.Errorf(clierror.Usage, "Provide item to the dev command")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(devCmd)
+}
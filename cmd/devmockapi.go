@@ -0,0 +1,120 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"github.com/sharon-vendrov/sharoncli/pkg/mockapi"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+type devMockAPICmdOptions struct {
+	address  string
+	token    string
+	scenario string
+	context  string
+}
+
+var devMockAPIOptions = &devMockAPICmdOptions{}
+
+// devMockAPICmd represents the dev mock-api command
+var devMockAPICmd = &cobra.Command{
+	Use:   "mock-api",
+	Short: "Serve a mock Codefresh API",
+	Long: `Serve the part of the Codefresh API that sharoncli uses from memory, so test runtime and
+create runtime --only-runtime-environment run without a Codefresh account.
+Builds succeed after 10s unless the scenario file scripts another outcome`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scenario := &mockapi.Scenario{}
+		if devMockAPIOptions.scenario != "" {
+			var err error
+			if scenario, err = mockapi.LoadScenario(devMockAPIOptions.scenario); err != nil {
+				return clierror.New(clierror.Config, err)
+			}
+		}
+		listener, err := net.Listen("tcp", devMockAPIOptions.address)
+		if err != nil {
+			return clierror.New(clierror.Config, err)
+		}
+		url := fmt.Sprintf("http://%s", listener.Addr())
+		server := &http.Server{Handler: mockapi.New(&mockapi.Options{
+			Token:    devMockAPIOptions.token,
+			Scenario: scenario,
+			Logger:   lgr,
+		})}
+
+		cfConfig := ""
+		if devMockAPIOptions.context != "" {
+			if cfConfig, err = writeMockConfig(devMockAPIOptions.context, url, devMockAPIOptions.token); err != nil {
+				return clierror.New(clierror.Config, err)
+			}
+			defer func() {
+				if err := os.Remove(cfConfig); err != nil {
+					lgr.Warn("Failed to remove the codefresh config file of the mock", "Path", cfConfig, "Error", err)
+				}
+			}()
+		}
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- server.Serve(listener)
+		}()
+		lgr.Info("Mock Codefresh API is running, press Ctrl-C to stop", "URL", url)
+		if cfConfig != "" {
+			lgr.Info("Run the commands with the codefresh config file of the mock", "Flag", "--cfconfig "+cfConfig)
+		}
+		select {
+		case err := <-errs:
+			return clierror.New(clierror.Unknown, err)
+		case <-cmdCtx.Done():
+			lgr.Info("Stopping the mock Codefresh API")
+			return server.Close()
+		}
+	},
+}
+
+func init() {
+	devMockAPICmd.Flags().StringVar(&devMockAPIOptions.address, "address", "127.0.0.1:8080", "Address to listen on, port 0 picks a free port")
+	devMockAPICmd.Flags().StringVar(&devMockAPIOptions.token, "token", mockapi.DefaultToken, "API key the mock accepts")
+	devMockAPICmd.Flags().StringVar(&devMockAPIOptions.scenario, "scenario", "", "Yaml file with the pipelines, runtime environments and build outcomes of the mock")
+	devMockAPICmd.Flags().StringVar(&devMockAPIOptions.context, "cfconfig-context", "", "Write a temporary codefresh config file with a context of this name for the mock, to use with --cfconfig while the mock runs")
+	devCmd.AddCommand(devMockAPICmd)
+}
+
+// writeMockConfig writes a temporary codefresh config file whose current context is the mock and returns its path
+func writeMockConfig(name string, url string, token string) (string, error) {
+	data, err := yaml.Marshal(map[string]interface{}{
+		"contexts": map[string]interface{}{
+			name: map[string]interface{}{
+				"type":  "APIKey",
+				"name":  name,
+				"url":   url,
+				"token": token,
+			},
+		},
+		"current-context": name,
+	})
+	if err != nil {
+		return "", err
+	}
+	file, err := ioutil.TempFile("", "sharoncli-mock-*.cfconfig")
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), file.Close()
+}
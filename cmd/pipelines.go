@@ -0,0 +1,25 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"github.com/spf13/cobra"
+)
+
+// pipelinesCmd represents the pipelines command
+var pipelinesCmd = &cobra.Command{
+	Use:     "pipelines",
+	Aliases: []string{"pipeline"},
+	Short:   "Manage Codefresh pipelines",
+	Long:    `Manage Codefresh pipelines`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return clierror.Errorf(clierror.Usage, "Provide item to the pipelines command")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pipelinesCmd)
+}
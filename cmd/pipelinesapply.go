@@ -0,0 +1,59 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"github.com/sharon-vendrov/sharoncli/pkg/logic"
+	"github.com/spf13/cobra"
+)
+
+type pipelinesApplyCmdOptions struct {
+	file   string
+	dryRun bool
+}
+
+var pipelinesApplyOptions = &pipelinesApplyCmdOptions{}
+
+// pipelinesApplyCmd represents the pipelines apply command
+var pipelinesApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Create or update pipelines from a file",
+	Long:  `Create or update pipelines from a yaml or json file, the diff with the pipelines in Codefresh is shown before they are changed`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if pipelinesApplyOptions.file == "" {
+			return clierror.Errorf(clierror.Usage, "--file is required")
+		}
+		specs, err := logic.ReadPipelineSpecs(pipelinesApplyOptions.file)
+		if err != nil {
+			return err
+		}
+		s, err := newService()
+		if err != nil {
+			return err
+		}
+		results, err := s.ApplyPipelines(cmdCtx, specs, &logic.ApplyOptions{
+			DryRun: pipelinesApplyOptions.dryRun,
+			OnDiff: func(name string, diff string) {
+				// stderr, stdout is kept for the command output
+				fmt.Fprint(os.Stderr, diff)
+			},
+		})
+		if printErr := printResult(results); printErr != nil && err == nil {
+			err = printErr
+		}
+		return err
+	},
+}
+
+func init() {
+	pipelinesApplyCmd.Flags().StringVarP(&pipelinesApplyOptions.file, "file", "f", "", "File with the pipelines, - for stdin")
+	pipelinesApplyCmd.Flags().BoolVar(&pipelinesApplyOptions.dryRun, "dry-run", false, "Show the diff without changing the pipelines")
+	pipelinesCmd.AddCommand(pipelinesApplyCmd)
+}
@@ -0,0 +1,33 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// pipelinesExportCmd represents the pipelines export command
+var pipelinesExportCmd = &cobra.Command{
+	Use:               "export <name>",
+	Short:             "Export a pipeline specification",
+	Long:              `Export a pipeline specification without the fields managed by Codefresh, the output can be applied back with pipelines apply`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePipelineArg,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := newService()
+		if err != nil {
+			return err
+		}
+		pipeline, err := s.ExportPipeline(cmdCtx, args[0])
+		if err != nil {
+			return err
+		}
+		return printResult(pipeline)
+	},
+}
+
+func init() {
+	pipelinesCmd.AddCommand(pipelinesExportCmd)
+}
@@ -0,0 +1,33 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// pipelinesGetCmd represents the pipelines get command
+var pipelinesGetCmd = &cobra.Command{
+	Use:               "get <name>",
+	Short:             "Show a pipeline",
+	Long:              `Show the spec, triggers and runtime environment of a pipeline, use -o yaml for the whole spec`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePipelineArg,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := newService()
+		if err != nil {
+			return err
+		}
+		pipeline, err := s.GetPipeline(cmdCtx, args[0])
+		if err != nil {
+			return err
+		}
+		return printResult(pipeline)
+	},
+}
+
+func init() {
+	pipelinesCmd.AddCommand(pipelinesGetCmd)
+}
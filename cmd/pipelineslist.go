@@ -0,0 +1,41 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"github.com/sharon-vendrov/sharoncli/pkg/logic"
+	"github.com/spf13/cobra"
+)
+
+var pipelinesListOptions = &logic.ListPipelinesOptions{}
+
+// pipelinesListCmd represents the pipelines list command
+var pipelinesListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List pipelines",
+	Long:    `List pipelines, filtered by project, labels and name`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := newService()
+		if err != nil {
+			return err
+		}
+		pipelines, err := s.ListPipelines(cmdCtx, pipelinesListOptions)
+		if err != nil {
+			return err
+		}
+		return printResult(pipelines)
+	},
+}
+
+func init() {
+	pipelinesListCmd.Flags().StringVar(&pipelinesListOptions.Project, "project", "", "Only pipelines of this project")
+	pipelinesListCmd.Flags().StringArrayVar(&pipelinesListOptions.Labels, "label", []string{}, "Only pipelines with this label, can be repeated")
+	pipelinesListCmd.Flags().StringVar(&pipelinesListOptions.NameRegex, "name-regex", "", "Only pipelines whose full name (project/pipeline) matches this regular expression")
+	pipelinesListCmd.Flags().IntVar(&pipelinesListOptions.Limit, "limit", 0, "Number of pipelines in a page, 0 for all")
+	pipelinesListCmd.Flags().IntVar(&pipelinesListOptions.Page, "page", 1, "Page to show when --limit is set")
+	pipelinesCmd.AddCommand(pipelinesListCmd)
+}
@@ -0,0 +1,171 @@
+/*
+Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
+
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"github.com/sharon-vendrov/sharoncli/pkg/plugin"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// pluginCmd represents the plugin command
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "Commands added by executables on the PATH",
+	Long: `Every sharoncli-<name> executable on the PATH is run by sharoncli <name>, with the arguments that follow.
+The plugin gets the settings sharoncli resolved in its environment: SHARONCLI_CODEFRESH_CONTEXT, SHARONCLI_CODEFRESH_URL,
+SHARONCLI_CFCONFIG, SHARONCLI_KUBECONFIG, SHARONCLI_OUTPUT, SHARONCLI_CONFIG and SHARONCLI_BIN`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return clierror.Errorf(clierror.Usage, "Provide item to the plugin command")
+	},
+}
+
+// pluginListCmd represents the plugin list command
+var pluginListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the plugins found on the PATH",
+	Long:  `List the sharoncli-<name> executables found on the PATH, with a warning for the ones sharoncli <name> does not run`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return printResult(findPlugins(cmd.Root()))
+	},
+}
+
+func init() {
+	pluginCmd.AddCommand(pluginListCmd)
+	rootCmd.AddCommand(pluginCmd)
+}
+
+// findPlugins returns the plugins on the PATH, a plugin with the name of a built-in command has a warning
+func findPlugins(root *cobra.Command) plugin.Plugins {
+	plugins := plugin.Find(os.Getenv("PATH"))
+	for _, p := range plugins {
+		if p.Warning != "" {
+			continue
+		}
+		if c, _, err := root.Find([]string{p.Name}); err == nil && c != root && !isPluginCommand(c) {
+			p.Warning = fmt.Sprintf("overridden by the built-in command %s", c.Name())
+		}
+	}
+	return plugins
+}
+
+// addPluginCommand adds the command of the plugin named by the first argument. The PATH is only searched
+// when the first argument is not a built-in command, so the built-in commands and the completion do not read it
+func addPluginCommand(root *cobra.Command, args []string) {
+	if len(args) == 0 || args[0] == "help" || strings.HasPrefix(args[0], "__") {
+		return
+	}
+	if c, _, err := root.Find(args[:1]); err == nil && c != root {
+		return
+	}
+	p := plugin.Lookup(os.Getenv("PATH"), args[0])
+	if p == nil {
+		return
+	}
+	root.AddCommand(&cobra.Command{
+		Use:         p.Name,
+		Short:       fmt.Sprintf("Plugin %s", p.Path),
+		Annotations: map[string]string{"plugin": p.Path},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPlugin(p, args)
+		},
+	})
+}
+
+// pluginArgs ends the flags of sharoncli after the name of a plugin, which is the first argument, so all
+// the arguments after it are passed to the plugin as they are
+func pluginArgs(root *cobra.Command, args []string) []string {
+	if len(args) == 0 {
+		return args
+	}
+	c, _, err := root.Find(args[:1])
+	if err != nil || !isPluginCommand(c) {
+		return args
+	}
+	return append([]string{args[0], "--"}, args[1:]...)
+}
+
+func isPluginCommand(c *cobra.Command) bool {
+	_, ok := c.Annotations["plugin"]
+	return ok
+}
+
+// runPlugin runs the plugin with the terminal of sharoncli, it exits with the exit code of the plugin.
+// On Ctrl-C the plugin gets the signal from the terminal, on SIGTERM or --timeout sharoncli sends it SIGTERM
+func runPlugin(p *plugin.Plugin, args []string) error {
+	c := exec.Command(p.Path, args...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Env = append(os.Environ(), pluginEnv()...)
+	lgr.Debug("Running plugin", "Plugin", p.Path, "Args", args)
+	if err := c.Start(); err != nil {
+		return fmt.Errorf("Failed to run plugin %s: %v", p.Path, err)
+	}
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-cmdCtx.Done():
+			c.Process.Signal(syscall.SIGTERM)
+		case <-exited:
+		}
+	}()
+	err := c.Wait()
+	exitErr := &exec.ExitError{}
+	if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+		return &clierror.ExitStatus{Command: filepath.Base(p.Path), Code: exitErr.ExitCode()}
+	}
+	if err != nil {
+		if ctxErr := clierror.FromContext(cmdCtx, fmt.Sprintf("Plugin %s", p.Name)); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("Plugin %s failed: %v", p.Path, err)
+	}
+	return nil
+}
+
+// pluginEnv passes the settings sharoncli resolved to the plugins
+func pluginEnv() []string {
+	env := []string{
+		"SHARONCLI_CFCONFIG=" + cfConfigPath(),
+		"SHARONCLI_KUBECONFIG=" + pluginKubeConfigPath(),
+		"SHARONCLI_OUTPUT=" + outputFormat,
+		"SHARONCLI_CONFIG=" + viper.ConfigFileUsed(),
+	}
+	if context, err := readCodefreshContext(cfConfigPath(), cfContext); err == nil && context != nil {
+		env = append(env, "SHARONCLI_CODEFRESH_CONTEXT="+context.Name, "SHARONCLI_CODEFRESH_URL="+context.URL)
+	}
+	if exe, err := os.Executable(); err == nil {
+		env = append(env, "SHARONCLI_BIN="+exe)
+	}
+	return env
+}
+
+// pluginKubeConfigPath is the kubeconfig kubectl would use
+func pluginKubeConfigPath() string {
+	if kubeConfigPath != "" {
+		return kubeConfigPath
+	}
+	if path := os.Getenv("KUBECONFIG"); path != "" {
+		return path
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kube", "config")
+}
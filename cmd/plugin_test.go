@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPluginArgs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"sharoncli-seed", "sharoncli-pipelines"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{"plugin", []string{"seed", "--output", "json", "run"}, []string{"seed", "--", "--output", "json", "run"}},
+		{"plugin without arguments", []string{"seed"}, []string{"seed", "--"}},
+		{"flag before the plugin", []string{"--output", "json", "seed"}, []string{"--output", "json", "seed"}},
+		{"plugin name as an argument", []string{"plugin", "seed"}, []string{"plugin", "seed"}},
+		{"built-in command", []string{"pipelines", "list"}, []string{"pipelines", "list"}},
+		{"completion", []string{"__complete", "seed", ""}, []string{"__complete", "seed", ""}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			addPluginCommand(rootCmd, test.args)
+			if args := pluginArgs(rootCmd, test.args); !reflect.DeepEqual(args, test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, args)
+			}
+		})
+	}
+	for _, c := range rootCmd.Commands() {
+		if isPluginCommand(c) {
+			if c.Name() != "seed" {
+				t.Errorf("unexpected plugin command %s", c.Name())
+			}
+			rootCmd.RemoveCommand(c)
+		}
+	}
+}
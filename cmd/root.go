@@ -7,16 +7,25 @@ package cmd
 import (
   "fmt"
   "os"
+  "strings"
+  "sync"
+  "time"
   "github.com/spf13/cobra"
 
   homedir "github.com/mitchellh/go-homedir"
   "github.com/spf13/viper"
 
+  "github.com/sharon-vendrov/sharoncli/pkg/apiretry"
+  "github.com/sharon-vendrov/sharoncli/pkg/clierror"
+  "github.com/sharon-vendrov/sharoncli/pkg/printer"
 )
 
 
 var cfgFile string
 
+// usageArgsOnce wraps the argument validators of the commands once
+var usageArgsOnce sync.Once
+
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -31,25 +40,88 @@ to quickly create a Cobra application.`,
   // Uncomment the following line if your bare application
   // has an action associated with it:
   //	Run: func(cmd *cobra.Command, args []string) { },
+  SilenceErrors: true,
+  PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+    // the config file sets the flags the command line did not set, its errors are not usage errors
+    ignored, err := applyConfig(cmd)
+    if err != nil {
+      cmd.SilenceUsage = true
+      return err
+    }
+    if _, err := printer.New(outputFormat, os.Stdout); err != nil {
+      return clierror.New(clierror.Usage, err)
+    }
+    // flags and args are valid from here on, failures should not print the usage
+    cmd.SilenceUsage = true
+    lgr, err = createLogger(commandName(cmd))
+    if err != nil {
+      return clierror.New(clierror.Config, err)
+    }
+    for _, problem := range ignored {
+      lgr.Warn("Ignoring an invalid key of the config file, see sharoncli config validate", "File", configFilePath(), "Key", problem.Key, "Problem", problem.Message)
+    }
+    if viper.ConfigFileUsed() != "" {
+      lgr.Debug("Using config file", "File", viper.ConfigFileUsed())
+    }
+    if err := setupAPITransport(); err != nil {
+      return err
+    }
+    cmdCtx, stopCommand = newCommandContext(cmd)
+    return nil
+  },
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// Errors are printed to stderr and mapped to the exit code of their clierror.Kind.
 func Execute() {
-  if err := rootCmd.Execute(); err != nil {
-    fmt.Println(err)
-    os.Exit(1)
+  err := execute(os.Args[1:])
+  stopCommand()
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+  }
+  os.Exit(clierror.ExitCode(err))
+}
+
+// execute runs the command of args, invalid arguments and unknown commands are usage errors
+func execute(args []string) error {
+  usageArgsOnce.Do(func() {
+    usageArgs(rootCmd)
+  })
+  addPluginCommand(rootCmd, args)
+  rootCmd.SetArgs(pluginArgs(rootCmd, args))
+  err := rootCmd.Execute()
+  if err != nil && clierror.KindOf(err) == clierror.Unknown && strings.HasPrefix(err.Error(), "unknown command ") {
+    return clierror.New(clierror.Usage, err)
   }
+  return err
 }
 
 func init() {
   cobra.OnInitialize(initConfig)
+  rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+    return clierror.New(clierror.Usage, err)
+  })
 
   // Here you will define your flags and configuration settings.
   // Cobra supports persistent flags, which, if defined here,
   // will be global for your application.
 
   rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.sharoncli.yaml)")
+  rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Profile of the config file to use, its settings apply to the flags that are not set (default is the profile key of the config file)")
+  rootCmd.PersistentFlags().StringVar(&configPath, "cfconfig", "", "Codefresh config file to read the contexts from (default is $HOME/.cfconfig)")
+  rootCmd.PersistentFlags().StringVar(&cfContext, "codefresh-context", "", "Context of the codefresh config file to use (default is its current-context)")
+  rootCmd.RegisterFlagCompletionFunc("profile", completeProfiles)
+  rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", printer.FormatTable, "Output format: "+strings.Join(printer.Formats, "|"))
+  rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Write the log of the run to this file (default is $HOME/.sharoncli/logs/<timestamp>-<command>.json)")
+  rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Format of the console log: text|json")
+  rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Minimal level of the console log: debug|info|warn|error|crit")
+  rootCmd.PersistentFlags().IntVar(&logMaxFiles, "log-max-files", 50, "Number of run logs to keep in $HOME/.sharoncli/logs, 0 to keep all")
+  rootCmd.PersistentFlags().DurationVar(&logMaxAge, "log-max-age", time.Duration(30*24)*time.Hour, "Remove run logs older than this from $HOME/.sharoncli/logs, 0 to keep all")
+  rootCmd.PersistentFlags().StringVar(&recordAPIDir, "record-api", "", "Record the Codefresh API requests and responses to this directory, tokens are redacted")
+  rootCmd.PersistentFlags().DurationVar(&commandTimeout, "timeout", 0, "Stop the command and clean up when it did not finish in this duration, 0 for no limit (default is no limit, 30m for test runtime)")
+  rootCmd.PersistentFlags().IntVar(&apiRetries, "api-retries", apiretry.DefaultRetries, "Number of times a Codefresh API request that failed with a transient error (connection, 429, 502, 503, 504) is retried, 0 to disable")
+  rootCmd.PersistentFlags().StringVar(&replayAPIDir, "replay-api", "", "Answer the Codefresh API requests from the recordings in this directory instead of the network")
 
 
   // Cobra also supports local flags, which will only run
@@ -78,9 +150,7 @@ func initConfig() {
 
   viper.AutomaticEnv() // read in environment variables that match
 
-  // If a config file is found, read it in.
-  if err := viper.ReadInConfig(); err == nil {
-    fmt.Println("Using config file:", viper.ConfigFileUsed())
-  }
+  // If a config file is found, read it in, stdout is kept for the output of the command
+  viper.ReadInConfig()
 }
 
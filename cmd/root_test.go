@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+)
+
+// useHome makes a temporary directory the HOME of the commands, homedir caches it so its cache is reset.
+// The returned func restores the previous HOME and removes the directory
+func useHome(t *testing.T) (string, func()) {
+	home, err := ioutil.TempDir("", "home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	homedir.Reset()
+	return home, func() {
+		os.Setenv("HOME", previous)
+		homedir.Reset()
+		os.RemoveAll(home)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	_, restore := useHome(t)
+	defer restore()
+	rootCmd.SetOut(ioutil.Discard)
+	rootCmd.SetErr(ioutil.Discard)
+	defer rootCmd.SetOut(nil)
+	defer rootCmd.SetErr(nil)
+
+	tests := map[string][]string{
+		"missing argument":     {"pipelines", "get"},
+		"extra argument":       {"builds", "cancel", "1", "2"},
+		"argument of no args":  {"pipelines", "list", "extra"},
+		"invalid valid arg":    {"completion", "powershell"},
+		"unknown command":      {"no-such-command"},
+		"unknown command flag": {"--output", "json", "no-such-command"},
+	}
+	for name, args := range tests {
+		err := execute(args)
+		if code := clierror.ExitCode(err); code != clierror.Usage.ExitCode() {
+			t.Errorf("%s: expected exit code %d, got %d for %v", name, clierror.Usage.ExitCode(), code, err)
+		}
+	}
+}
@@ -5,27 +5,297 @@ Copyright © 2019 Sharon Vendrov <sharon.vendrov1@gmail.com>
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"github.com/sharon-vendrov/sharoncli/pkg/hooks"
 	"github.com/sharon-vendrov/sharoncli/pkg/logic"
+	"github.com/sharon-vendrov/sharoncli/pkg/report"
 	"github.com/spf13/cobra"
 )
 
-var pipelineName string
+type testRuntimeCmdOptions struct {
+	pipelineNames     []string
+	pipelinesFile     string
+	parallelism       int
+	detach            bool
+	branch            string
+	sha               string
+	trigger           string
+	variables         []string
+	varFiles          []string
+	noCache           bool
+	resetVolume       bool
+	reports           []string
+	cancelOnInterrupt bool
+	logs              bool
+
+	runtimeEnvironment string
+	kubeContext        string
+}
+
+var testRuntimeOptions = &testRuntimeCmdOptions{}
 
 // testruntimeCmd represents the testruntime command
 var testruntimeCmd = &cobra.Command{
 	Use:   "runtime",
 	Short: "execute pipeline",
-	Long:  `execute pipeline`,
-	Run: func(cmd *cobra.Command, args []string) {
-		err := logic.ExecutePipeline(pipelineName)
+	Long:  `execute pipelines and wait until the builds finish, the command succeeds only if all the builds succeeded`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reports := []*report.Report{}
+		for _, value := range testRuntimeOptions.reports {
+			r, err := report.Parse(value)
+			if err != nil {
+				return clierror.New(clierror.Usage, err)
+			}
+			reports = append(reports, r)
+		}
+		variables, err := logic.ParseVariables(testRuntimeOptions.variables, testRuntimeOptions.varFiles)
 		if err != nil {
-			panic("fail to run pipeline")
+			return err
+		}
+		runtimeEnvironment, err := testRuntimeEnvironment(testRuntimeOptions.runtimeEnvironment, testRuntimeOptions.kubeContext)
+		if err != nil {
+			return err
+		}
+		hookRunner, err := newHookRunner()
+		if err != nil {
+			return err
+		}
+		names := testRuntimeOptions.pipelineNames
+		// buildsRunning is set when builds were left running, the smoke test pipeline is kept for them
+		buildsRunning := false
+		if testRuntimeOptions.pipelinesFile != "" {
+			fileNames, err := logic.ReadPipelinesFile(testRuntimeOptions.pipelinesFile)
+			if err != nil {
+				return err
+			}
+			names = append(names, fileNames...)
+		}
+		s, err := newService()
+		if err != nil {
+			return err
+		}
+		if runtimeEnvironment != "" {
+			if err := s.CheckRuntimeEnvironment(runtimeEnvironment); err != nil {
+				return err
+			}
+		}
+		if len(names) == 0 {
+			if testRuntimeOptions.detach {
+				return clierror.Errorf(clierror.Usage, "--detach requires --name, the smoke test pipeline is deleted after the build")
+			}
+			pipelineName, deletePipeline, err := s.CreateSmokeTestPipeline(cmdCtx, runtimeEnvironment)
+			if err != nil {
+				return err
+			}
+			lgr.Info("Created smoke test pipeline", "Pipeline", pipelineName)
+			defer func() {
+				if buildsRunning {
+					lgr.Warn("Keeping the smoke test pipeline of the running build", "Pipeline", pipelineName)
+					return
+				}
+				if err := deletePipeline(); err != nil {
+					lgr.Warn("Failed to delete the smoke test pipeline", "Pipeline", pipelineName, "Error", err)
+					return
+				}
+				lgr.Info("Deleted smoke test pipeline", "Pipeline", pipelineName)
+			}()
+			names = []string{pipelineName}
+		}
+
+		var wait *logic.WaitOptions
+		if !testRuntimeOptions.detach {
+			wait = &logic.WaitOptions{
+				Interval: 5 * time.Second,
+				OnStep: func(build *logic.Build, step *cfapi.Step) {
+					lgr.Info("Step "+step.Status, "Pipeline", build.Pipeline, "Build-ID", build.ID, "Step", step.Name, "Duration", step.Duration().Round(time.Second))
+				},
+			}
+			if testRuntimeOptions.logs {
+				wait.Logs = os.Stderr
+			}
+		}
+		builds, errs := s.RunPipelines(cmdCtx, names, &logic.RunOptions{
+			Branch:      testRuntimeOptions.branch,
+			SHA:         testRuntimeOptions.sha,
+			Trigger:     testRuntimeOptions.trigger,
+			Variables:   variables,
+			NoCache:     testRuntimeOptions.noCache,
+			ResetVolume: testRuntimeOptions.resetVolume,
+
+			RuntimeEnvironment: runtimeEnvironment,
+		}, wait, testRuntimeOptions.parallelism)
+		if cmdCtx.Err() == context.Canceled && !testRuntimeOptions.detach {
+			cancelInterrupted(s, builds, testRuntimeOptions.cancelOnInterrupt)
+		}
+		// after --timeout, an error while waiting or a failed cancel
+		buildsRunning = len(runningBuilds(builds)) > 0
+		err = logic.FirstError(errs)
+		if !testRuntimeOptions.detach && cmdCtx.Err() == nil {
+			rt := testHookRuntime(runtimeEnvironment, testRuntimeOptions.kubeContext)
+			rt.Result = "passed"
+			if err != nil {
+				rt.Result = "failed"
+			}
+			if hookErr := hookRunner.Run(cmdCtx, hooks.PostTest, rt); hookErr != nil && err == nil {
+				err = hookErr
+			}
+		}
+		printed, reportErr := writeReports(reports, builds)
+		if reportErr != nil && err == nil {
+			err = reportErr
+		}
+		if printed {
+			return err
 		}
+		if printErr := printResult(builds); printErr != nil && err == nil {
+			err = printErr
+		}
+		return err
 	},
 }
 
 func init() {
-	testruntimeCmd.Flags().StringVar(&pipelineName, "name", "", "pipeline name")
+	testruntimeCmd.Flags().StringArrayVar(&testRuntimeOptions.pipelineNames, "name", []string{}, "pipeline name, can be repeated (default is a built-in smoke test pipeline that is deleted after the build)")
+	testruntimeCmd.Flags().StringVar(&testRuntimeOptions.pipelinesFile, "pipelines-file", "", "File with pipeline names to run, one per line")
+	testruntimeCmd.Flags().IntVar(&testRuntimeOptions.parallelism, "parallelism", 4, "Number of pipelines to run at the same time")
+	testruntimeCmd.Flags().BoolVar(&testRuntimeOptions.detach, "detach", false, "Do not wait for the build, print its ID and exit")
+	testruntimeCmd.Flags().StringVar(&testRuntimeOptions.branch, "branch", "", "Branch to build, validated against the repository of the git trigger (default is the branch of the trigger)")
+	testruntimeCmd.Flags().StringVar(&testRuntimeOptions.sha, "sha", "", "Commit to build")
+	testruntimeCmd.Flags().StringVar(&testRuntimeOptions.trigger, "trigger", "", "Name of the git trigger to run the pipeline with")
+	testruntimeCmd.Flags().StringArrayVar(&testRuntimeOptions.variables, "variable", []string{}, "Variable of the build in KEY=VALUE format, can be repeated")
+	testruntimeCmd.Flags().StringArrayVar(&testRuntimeOptions.varFiles, "var-file", []string{}, "File with variables of the build (.yaml, .json or .env), can be repeated")
+	testruntimeCmd.Flags().BoolVar(&testRuntimeOptions.noCache, "no-cache", false, "Ignore the docker layer cache")
+	testruntimeCmd.Flags().BoolVar(&testRuntimeOptions.resetVolume, "reset-volume", false, "Reset the pipeline volume before the build")
+	testruntimeCmd.Flags().StringVar(&testRuntimeOptions.runtimeEnvironment, "runtime-environment", "", "Run the pipeline on this runtime environment (default is the one created by the last create runtime)")
+	testruntimeCmd.Flags().StringVar(&testRuntimeOptions.kubeContext, "kube-context-name", "", "Use the runtime environment created by create runtime for this kubernetes context (default is the last created)")
+	testruntimeCmd.Flags().BoolVar(&testRuntimeOptions.logs, "logs", false, "Stream the output of the build steps to stderr while waiting")
+	testruntimeCmd.Flags().BoolVar(&testRuntimeOptions.cancelOnInterrupt, "cancel-on-interrupt", false, "Cancel the started builds on Ctrl-C without asking")
+	testruntimeCmd.Flags().StringArrayVar(&testRuntimeOptions.reports, "report", []string{}, "Write a test report: junit=<path>, tap or tap=<path>, without a path the report replaces the output, can be repeated")
+	testruntimeCmd.RegisterFlagCompletionFunc("name", completePipelineNames)
+	testruntimeCmd.RegisterFlagCompletionFunc("runtime-environment", completeRuntimeEnvironments)
+	testruntimeCmd.RegisterFlagCompletionFunc("kube-context-name", completeKubeContexts)
 	testCmd.AddCommand(testruntimeCmd)
+	defaultTimeouts[testruntimeCmd] = 30 * time.Minute
+
+}
+
+// testRuntimeEnvironment returns the runtime environment to run the test on,
+// empty means the runtime environment configured in the pipeline
+func testRuntimeEnvironment(name string, kubeContext string) (string, error) {
+	if name != "" {
+		return name, nil
+	}
+	st, err := loadState()
+	if err != nil {
+		return "", clierror.New(clierror.Config, err)
+	}
+	r := st.Runtime(kubeContext)
+	if r == nil {
+		if kubeContext != "" {
+			return "", clierror.Errorf(clierror.Config, "No runtime was created for kubernetes context %s, set --runtime-environment", kubeContext)
+		}
+		lgr.Debug("No runtime was created yet, using the runtime environment of the pipeline")
+		return "", nil
+	}
+	lgr.Info("Using runtime environment of the last created runtime", "Runtime-Environment", r.RuntimeEnvironment, "Kube-Context", r.KubeContext)
+	return r.RuntimeEnvironment, nil
+}
 
+// testHookRuntime describes the runtime the builds ran on to the hooks, the kube settings are known
+// when the runtime environment was created by create runtime
+func testHookRuntime(runtimeEnvironment string, kubeContext string) *hooks.Runtime {
+	rt := &hooks.Runtime{RuntimeEnvironment: runtimeEnvironment}
+	st, err := loadState()
+	if err != nil {
+		return rt
+	}
+	if r := st.Runtime(kubeContext); r != nil && r.RuntimeEnvironment == runtimeEnvironment {
+		rt.KubeConfig = r.KubeConfig
+		rt.KubeContext = r.KubeContext
+		rt.Namespace = r.Namespace
+	}
+	return rt
+}
+
+// writeReports writes the reports of the builds, printed is true when a report was written to stdout
+func writeReports(reports []*report.Report, builds logic.Builds) (printed bool, err error) {
+	for _, r := range reports {
+		if r.Path == "" {
+			printed = true
+			if err := r.Write(os.Stdout, builds); err != nil {
+				return printed, err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(r.Path), 0755); err != nil {
+			return printed, err
+		}
+		f, err := os.Create(r.Path)
+		if err != nil {
+			return printed, err
+		}
+		err = r.Write(f, builds)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return printed, err
+		}
+		lgr.Info("Wrote test report", "Format", r.Format, "Path", r.Path)
+	}
+	return printed, nil
+}
+
+// runningBuilds returns the started builds that did not finish and were not cancelled
+func runningBuilds(builds logic.Builds) logic.Builds {
+	running := logic.Builds{}
+	for _, build := range builds {
+		if build.ID != "" && !cfapi.IsTerminal(build.Status) && build.Reason != "cancelled" {
+			running = append(running, build)
+		}
+	}
+	return running
+}
+
+// cancelInterrupted cancels the builds that were still running when test runtime was interrupted,
+// automatically when cancel is set or after confirmation on a terminal. The cancelled builds get the
+// reason cancelled
+func cancelInterrupted(s *logic.Service, builds logic.Builds, cancel bool) {
+	running := runningBuilds(builds)
+	ids := []string{}
+	for _, build := range running {
+		ids = append(ids, build.ID)
+	}
+	if len(running) == 0 {
+		return
+	}
+	if !cancel && isatty.IsTerminal(os.Stdin.Fd()) {
+		fmt.Fprintf(os.Stderr, "\nCancel the builds %s? [y/N]: ", strings.Join(ids, ", "))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		cancel = answer == "y" || answer == "yes"
+	}
+	if !cancel {
+		lgr.Warn("Interrupted, the builds keep running", "Build-IDs", strings.Join(ids, ","))
+		return
+	}
+	// cmdCtx is done, the builds are cancelled without it
+	for _, build := range running {
+		if err := s.CancelBuild(context.Background(), build.ID); err != nil {
+			lgr.Warn("Failed to cancel build", "Build-ID", build.ID, "Error", err)
+			continue
+		}
+		build.Reason = "cancelled"
+		lgr.Info("Cancelled build", "Build-ID", build.ID)
+	}
 }
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/codefresh-io/go-sdk/pkg/codefresh"
+	log "github.com/inconshreveable/log15"
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"github.com/sharon-vendrov/sharoncli/pkg/logic"
+	"github.com/sharon-vendrov/sharoncli/pkg/mockapi"
+	"github.com/spf13/pflag"
+)
+
+// startBuilds runs demo/slow that is still running and demo/fast that finished on the mock
+func startBuilds(t *testing.T) (*logic.Service, logic.Builds, func()) {
+	l := log.New()
+	l.SetHandler(log.DiscardHandler())
+	lgr = l
+	server := httptest.NewServer(mockapi.New(&mockapi.Options{Scenario: &mockapi.Scenario{
+		Pipelines: []map[string]interface{}{
+			{"metadata": map[string]interface{}{"name": "demo/slow"}},
+			{"metadata": map[string]interface{}{"name": "demo/fast"}},
+		},
+		Builds: []*mockapi.Outcome{
+			{Pipeline: "demo/slow", Status: cfapi.StatusSuccess, Duration: mockapi.Duration{Duration: time.Hour}},
+			{Pipeline: "demo/fast", Status: cfapi.StatusSuccess, Duration: mockapi.Duration{Duration: time.Millisecond}},
+		},
+	}}))
+	s := logic.New(&logic.Options{
+		Codefresh: codefresh.New(&codefresh.ClientOptions{Host: server.URL, Auth: codefresh.AuthOptions{Token: mockapi.DefaultToken}}),
+		API:       cfapi.New(&cfapi.Options{Host: server.URL, Token: mockapi.DefaultToken}),
+		Host:      server.URL,
+	})
+	builds := logic.Builds{{Pipeline: "demo/never-started"}}
+	for _, name := range []string{"demo/slow", "demo/fast"} {
+		build, err := s.ExecutePipeline(context.Background(), name, &logic.RunOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		build.Status = cfapi.StatusRunning
+		builds = append(builds, build)
+	}
+	time.Sleep(10 * time.Millisecond)
+	builds[2].Status = cfapi.StatusSuccess
+	return s, builds, server.Close
+}
+
+func TestCancelInterrupted(t *testing.T) {
+	s, builds, stop := startBuilds(t)
+	defer stop()
+	cancelInterrupted(s, builds, true)
+	if running := runningBuilds(builds); len(running) != 0 {
+		t.Fatalf("runningBuilds() = %d builds after the cancel, want none", len(running))
+	}
+	slow, fast := builds[1], builds[2]
+	if slow.Reason != "cancelled" || fast.Reason != "" || builds[0].Reason != "" {
+		t.Errorf("cancelInterrupted() reasons = %q, %q, %q, want only the running build cancelled", builds[0].Reason, slow.Reason, fast.Reason)
+	}
+	if err := s.CancelBuild(context.Background(), slow.ID); err == nil {
+		t.Error("the running build was not terminated")
+	}
+}
+
+func TestCancelInterruptedKeepsBuilds(t *testing.T) {
+	s, builds, stop := startBuilds(t)
+	defer stop()
+	// without a terminal on stdin the builds are not cancelled without --cancel-on-interrupt
+	stdin, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(f *os.File) { os.Stdin = f }(os.Stdin)
+	os.Stdin = stdin
+	defer stdin.Close()
+	cancelInterrupted(s, builds, false)
+	if running := runningBuilds(builds); len(running) != 1 || running[0] != builds[1] {
+		t.Fatalf("runningBuilds() = %d builds, want the slow build to keep running", len(running))
+	}
+	if builds[1].Reason != "" {
+		t.Errorf("the running build was cancelled: %+v", builds[1])
+	}
+	if err := s.CancelBuild(context.Background(), builds[1].ID); err != nil {
+		t.Errorf("the running build is not running anymore: %v", err)
+	}
+}
+
+func TestCancelInterruptedFinished(t *testing.T) {
+	s, builds, stop := startBuilds(t)
+	defer stop()
+	cancelInterrupted(s, builds[2:], false)
+	if running := runningBuilds(builds[2:]); len(running) != 0 {
+		t.Errorf("runningBuilds() = %d builds that finished", len(running))
+	}
+}
+
+func TestCancelInterruptedFailed(t *testing.T) {
+	s, builds, stop := startBuilds(t)
+	// the cancel fails when the API is gone, the build keeps running
+	stop()
+	cancelInterrupted(s, builds, true)
+	if running := runningBuilds(builds); len(running) != 1 || builds[1].Reason != "" {
+		t.Errorf("runningBuilds() = %d builds after a failed cancel, want the slow build", len(running))
+	}
+}
+
+func TestTestRuntimeOfflineRuntimeEnvironment(t *testing.T) {
+	_, restore := useHome(t)
+	defer restore()
+	defer func(path string, options testRuntimeCmdOptions) {
+		configPath = path
+		*testRuntimeOptions = options
+		testruntimeCmd.Flags().VisitAll(func(f *pflag.Flag) { f.Changed = false })
+		cfAPIHost = ""
+		cfAPIToken = ""
+	}(configPath, *testRuntimeOptions)
+
+	mock := mockapi.New(&mockapi.Options{Scenario: &mockapi.Scenario{
+		RuntimeEnvironments: []*mockapi.RuntimeEnvironment{{Name: "kind/offline", Offline: true}},
+	}})
+	created := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/api/pipelines" {
+			created++
+		}
+		mock.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	cfConfig, err := writeMockConfig("mock", server.URL, mockapi.DefaultToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(cfConfig)
+
+	err = execute([]string{"test", "runtime", "--cfconfig", cfConfig, "--runtime-environment", "kind/offline", "--api-retries", "0"})
+	if clierror.KindOf(err) != clierror.API || created != 0 {
+		t.Errorf("expected an API error before the smoke test pipeline is created, got %v and %d pipelines", err, created)
+	}
+}
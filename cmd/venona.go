@@ -17,23 +17,41 @@ limitations under the License.
 */
 
 import (
+	"context"
 	"fmt"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/codefresh-io/venona/venonactl/pkg/kube"
 	"github.com/codefresh-io/venona/venonactl/pkg/plugins"
 	"github.com/codefresh-io/venona/venonactl/pkg/store"
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"github.com/sharon-vendrov/sharoncli/pkg/progress"
+	"github.com/sharon-vendrov/sharoncli/pkg/state"
 )
 
+// agentWaitInterval is how often create runtime checks the agent once it is installed
+const agentWaitInterval = 5 * time.Second
+
 // installCmd represents the install command
-func installVenona(installCmdOptions venonaInstallCmdOptions) {
+func installVenona(ctx context.Context, rep *progress.Reporter, installCmdOptions venonaInstallCmdOptions) error {
 	s := store.GetStore()
-	lgr := createLogger("Install", verbose)
 	buildBasicStore(lgr)
-	extendStoreWithCodefershClient(lgr)
+	if err := extendStoreWithCodefershClient(lgr); err != nil {
+		return err
+	}
 	extendStoreWithKubeClient(lgr)
 
 	builder := plugins.NewBuilder(lgr)
+	// pluginTypes are the plugins added to the builder, in the order they are installed
+	pluginTypes := []string{}
+	add := func(t string) {
+		builder.Add(t)
+		pluginTypes = append(pluginTypes, t)
+	}
 	isDefault := isUsingDefaultStorageClass(installCmdOptions.storageClass)
 
 	builderInstallOpt := &plugins.InstallOptions{
@@ -47,7 +65,7 @@ func installVenona(installCmdOptions venonaInstallCmdOptions) {
 	}
 
 	if installCmdOptions.kubernetesRunnerType {
-		builder.Add(plugins.EnginePluginType)
+		add(plugins.EnginePluginType)
 	}
 
 	if isDefault {
@@ -55,7 +73,10 @@ func installVenona(installCmdOptions venonaInstallCmdOptions) {
 	}
 
 	if installCmdOptions.kube.context == "" {
-		config := clientcmd.GetConfigFromFileOrDie(s.KubernetesAPI.ConfigPath)
+		config, err := clientcmd.LoadFromFile(s.KubernetesAPI.ConfigPath)
+		if err != nil {
+			return clierror.Errorf(clierror.Config, "Failed to read kubeconfig %s: %v", s.KubernetesAPI.ConfigPath, err)
+		}
 		installCmdOptions.kube.context = config.CurrentContext
 		lgr.Debug("Kube Context is not set, using current context", "Kube-Context-Name", installCmdOptions.kube.context)
 	}
@@ -80,24 +101,23 @@ func installVenona(installCmdOptions venonaInstallCmdOptions) {
 	}
 	s.ClusterInCodefresh = installCmdOptions.clusterNameInCodefresh
 	if installCmdOptions.installOnlyRuntimeEnvironment == true && installCmdOptions.skipRuntimeInstallation == true {
-		dieOnError(fmt.Errorf("Cannot use both flags skip-runtime-installation and only-runtime-environment"))
+		return clierror.Errorf(clierror.Usage, "Cannot use both flags skip-runtime-installation and only-runtime-environment")
 	}
 	if installCmdOptions.installOnlyRuntimeEnvironment == true {
-		builder.Add(plugins.RuntimeEnvironmentPluginType)
+		add(plugins.RuntimeEnvironmentPluginType)
 	} else if installCmdOptions.skipRuntimeInstallation == true {
 		if installCmdOptions.runtimeEnvironmentName == "" {
-			dieOnError(fmt.Errorf("runtime-environment flag is required when using flag skip-runtime-installation"))
+			return clierror.Errorf(clierror.Usage, "runtime-environment flag is required when using flag skip-runtime-installation")
 		}
 		s.RuntimeEnvironment = installCmdOptions.runtimeEnvironmentName
 		lgr.Info("Skipping installation of runtime environment, installing venona only")
-		builder.Add(plugins.VenonaPluginType)
+		add(plugins.VenonaPluginType)
 	} else {
-		builder.
-			Add(plugins.RuntimeEnvironmentPluginType).
-			Add(plugins.VenonaPluginType)
+		add(plugins.RuntimeEnvironmentPluginType)
+		add(plugins.VenonaPluginType)
 	}
 	if isDefault {
-		builder.Add(plugins.VolumeProvisionerPluginType)
+		add(plugins.VolumeProvisionerPluginType)
 	} else {
 		lgr.Info("Custom StorageClass is set, skipping installation of default volume provisioner")
 	}
@@ -108,16 +128,102 @@ func installVenona(installCmdOptions venonaInstallCmdOptions) {
 		builderInstallOpt.ClusterName = s.ClusterInCodefresh
 		builderInstallOpt.RegisterWithAgent = false
 	}
-	builderInstallOpt.KubeBuilder = getKubeClientBuilder(builderInstallOpt.ClusterName, s.KubernetesAPI.Namespace, s.KubernetesAPI.ConfigPath, s.KubernetesAPI.InCluster)
+	kubeBuilder := getKubeClientBuilder(builderInstallOpt.ClusterName, s.KubernetesAPI.Namespace, s.KubernetesAPI.ConfigPath, s.KubernetesAPI.InCluster)
+	builderInstallOpt.KubeBuilder = kubeBuilder
 	builderInstallOpt.ClusterNamespace = s.KubernetesAPI.Namespace
 
 	values := s.BuildValues()
 	var err error
-	for _, p := range builder.Get() {
+	for i, p := range builder.Get() {
+		// the plugins cannot be stopped halfway, the installation stops between them
+		if err := clierror.FromContext(ctx, "Installing the runtime"); err != nil {
+			return err
+		}
+		phase := rep.Start(fmt.Sprintf("Install %s", pluginTypes[i]))
 		values, err = p.Install(builderInstallOpt, values)
 		if err != nil {
-			dieOnError(err)
+			return phase.Done(clierror.New(clierror.Install, err))
 		}
+		phase.Done(nil)
 	}
 	lgr.Info("Installation completed Successfully")
+
+	if installCmdOptions.dryRun {
+		return nil
+	}
+	runtimeEnvironment := s.RuntimeEnvironment
+	if runtimeEnvironment == "" {
+		runtimeEnvironment = fmt.Sprintf("%s/%s", builderInstallOpt.ClusterName, builderInstallOpt.ClusterNamespace)
+	}
+	if installCmdOptions.agentWait > 0 && contains(pluginTypes, plugins.VenonaPluginType) {
+		if err := waitForAgent(ctx, rep, installCmdOptions.agentWait, runtimeEnvironment, kubeBuilder, builderInstallOpt.ClusterNamespace); err != nil {
+			return err
+		}
+	}
+	if err := saveRuntime(&state.Runtime{
+		KubeContext:        s.KubernetesAPI.ContextName,
+		KubeConfig:         s.KubernetesAPI.ConfigPath,
+		Namespace:          s.KubernetesAPI.Namespace,
+		RuntimeEnvironment: runtimeEnvironment,
+		CreatedAt:          time.Now(),
+	}); err != nil {
+		lgr.Warn("Failed to save the runtime, test runtime will not use it by default", "Error", err)
+	}
+	return nil
+}
+
+// waitForAgent waits for the agent to register the runtime environment in Codefresh and for its
+// deployment to be available, each for at most timeout. The runtime is installed by then, a timeout is only a warning
+func waitForAgent(ctx context.Context, rep *progress.Reporter, timeout time.Duration, runtimeEnvironment string, kubeBuilder kube.Kube, namespace string) error {
+	phase := rep.Start("Wait for the agent to register")
+	err := waitFor(ctx, "the agent registration", timeout, agentWaitInterval, func() (bool, error) {
+		re, err := store.GetStore().CodefreshAPI.Client.RuntimeEnvironments().Get(runtimeEnvironment)
+		if err != nil {
+			lgr.Debug("Failed to get the runtime environment", "Runtime-Environment", runtimeEnvironment, "Error", err)
+			return false, nil
+		}
+		return cfapi.IsOnline(re), nil
+	})
+	if !endWait(ctx, phase, err) {
+		return err
+	}
+
+	phase = rep.Start("Wait for the agent to be ready")
+	cs, err := kubeBuilder.BuildClient()
+	if err != nil {
+		phase.Warn(err.Error())
+		return nil
+	}
+	err = waitFor(ctx, "the agent deployment", timeout, agentWaitInterval, func() (bool, error) {
+		d, err := cs.AppsV1().Deployments(namespace).Get(store.ApplicationName, metav1.GetOptions{})
+		if err != nil {
+			lgr.Debug("Failed to get the agent deployment", "Namespace", namespace, "Error", err)
+			return false, nil
+		}
+		return d.Status.AvailableReplicas > 0, nil
+	})
+	if !endWait(ctx, phase, err) {
+		return err
+	}
+	return nil
+}
+
+// endWait ends the phase of a wait that does not fail the command when the wait times out, it returns false
+// when the command should stop with err, also when ctx is done
+func endWait(ctx context.Context, phase *progress.Phase, err error) bool {
+	if ctx.Err() == nil && clierror.KindOf(err) == clierror.Timeout {
+		phase.Warn(err.Error())
+		return true
+	}
+	return phase.Done(err) == nil
+}
+
+// saveRuntime records the runtime as the last one created
+func saveRuntime(r *state.Runtime) error {
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	st.AddRuntime(r)
+	return st.Save()
 }
@@ -1,21 +1,52 @@
 package cmd
 
 import (
+	"context"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
-
-	"github.com/spf13/viper"
 )
 
+// TestInstallVenona simulates the installation on the kind cluster of testdata/kubeconfig, the Codefresh API
+// answers from testdata/venona, recorded with --record-api against dev mock-api
 func TestInstallVenona(t *testing.T) {
+	dir, err := ioutil.TempDir("", "venona")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(file string) { logFile = file }(logFile)
+	logFile = filepath.Join(dir, "install.json")
+	if lgr, err = createLogger("Install"); err != nil {
+		t.Fatal(err)
+	}
+	defer func(transport http.RoundTripper) {
+		http.DefaultTransport = transport
+		replayAPIDir = ""
+	}(http.DefaultTransport)
+	replayAPIDir = "testdata/venona"
+	if err := setupAPITransport(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(skip bool, options venonaInstallCmdOptions, host string, token string, kubeConfig string, cfConfig string) {
+		skipVerionCheck = skip
+		*installCmdOptions = options
+		cfAPIHost = host
+		cfAPIToken = token
+		kubeConfigPath = kubeConfig
+		configPath = cfConfig
+	}(skipVerionCheck, *installCmdOptions, cfAPIHost, cfAPIToken, kubeConfigPath, configPath)
+	skipVerionCheck = true
+	installCmdOptions.dryRun = true
 	installCmdOptions.kube.context = "kubernetes-admin@kind"
 	installCmdOptions.clusterNameInCodefresh = "kubernetes-admin@kind"
-	installCmdOptions.kube.namespace = viper.GetString("kube-namespace")
-
-	homePath := os.Getenv("HOME")
-	kubeConfigPath = homePath + "/.kube/kind-config-kind"
-
-	installVenona(*installCmdOptions)
+	installCmdOptions.kube.namespace = "codefresh"
+	kubeConfigPath = "testdata/kubeconfig"
 
-	// TODO get runtime environment and validate creation
+	if err := installVenona(context.Background(), newReporter(), *installCmdOptions); err != nil {
+		t.Fatal(err)
+	}
 }
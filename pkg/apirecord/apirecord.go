@@ -0,0 +1,170 @@
+package apirecord
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/codefresh-io/venona/venonactl/pkg/logger"
+	log "github.com/inconshreveable/log15"
+)
+
+// Redacted replaces the tokens in the recordings
+const Redacted = "REDACTED"
+
+type (
+	// Options of the Recorder and the Replayer
+	Options struct {
+		// Dir holds the recordings, one json file per request
+		Dir string
+		// Transport sends the requests of the Recorder, default is http.DefaultTransport
+		Transport http.RoundTripper
+		Logger    logger.Logger
+	}
+
+	// Exchange is a recorded request and its response, Error is set when no response was received
+	Exchange struct {
+		Request  *Request  `json:"request"`
+		Response *Response `json:"response,omitempty"`
+		Error    string    `json:"error,omitempty"`
+	}
+
+	// Request of an Exchange, URL is the path and query without the host
+	Request struct {
+		Method string      `json:"method"`
+		URL    string      `json:"url"`
+		Header http.Header `json:"header,omitempty"`
+		Body   *Body       `json:"body,omitempty"`
+	}
+
+	// Response of an Exchange
+	Response struct {
+		StatusCode int         `json:"statusCode"`
+		Header     http.Header `json:"header,omitempty"`
+		Body       *Body       `json:"body,omitempty"`
+	}
+
+	// Body is kept as text, or base64 when it is binary
+	Body struct {
+		Text   string `json:"text,omitempty"`
+		Base64 string `json:"base64,omitempty"`
+	}
+)
+
+// secretHeaders are replaced with Redacted
+var secretHeaders = []string{"Authorization", "X-Access-Token", "Cookie", "Set-Cookie"}
+
+// tokenHeaders carry a token that is also redacted from the urls and bodies
+var tokenHeaders = map[string]bool{"Authorization": true, "X-Access-Token": true}
+
+// secretResponses are the requests whose response body is a token
+var secretResponses = []*regexp.Regexp{
+	regexp.MustCompile(`^POST /api/auth/key\b`),
+}
+
+func newBody(data []byte) *Body {
+	if len(data) == 0 {
+		return nil
+	}
+	if utf8.Valid(data) {
+		return &Body{Text: string(data)}
+	}
+	return &Body{Base64: base64.StdEncoding.EncodeToString(data)}
+}
+
+// Bytes returns the content of the body
+func (b *Body) Bytes() ([]byte, error) {
+	if b == nil {
+		return nil, nil
+	}
+	if b.Base64 != "" {
+		return base64.StdEncoding.DecodeString(b.Base64)
+	}
+	return []byte(b.Text), nil
+}
+
+// key identifies the requests that are answered by the same recordings, the query parameters are sorted
+// because the sdk sends them in the random order of a map
+func (r *Request) key() string {
+	parts := strings.SplitN(r.URL, "?", 2)
+	if len(parts) == 2 {
+		if query, err := url.ParseQuery(parts[1]); err == nil {
+			return fmt.Sprintf("%s %s?%s", r.Method, parts[0], query.Encode())
+		}
+	}
+	return fmt.Sprintf("%s %s", r.Method, r.URL)
+}
+
+// requestURL returns the path and query of the request
+func requestURL(req *http.Request) string {
+	return req.URL.RequestURI()
+}
+
+// readExchanges reads the recordings of dir in the order they were recorded
+func readExchanges(dir string) ([]*Exchange, error) {
+	files, err := recordingFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	exchanges := []*Exchange{}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		e := &Exchange{}
+		if err := json.Unmarshal(data, e); err != nil {
+			return nil, fmt.Errorf("Failed to parse recording %s: %v", f, err)
+		}
+		if e.Request == nil {
+			return nil, fmt.Errorf("Recording %s has no request", f)
+		}
+		exchanges = append(exchanges, e)
+	}
+	return exchanges, nil
+}
+
+// recordingFiles returns the recordings of dir sorted by their sequence number
+func recordingFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return sequence(files[i]) < sequence(files[j])
+	})
+	return files, nil
+}
+
+// sequence returns the number that prefixes the name of a recording file
+func sequence(path string) int {
+	n := 0
+	fmt.Sscanf(filepath.Base(path), "%d-", &n)
+	return n
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// fileName of the n-th recording, e.g. 0003-get-api-pipelines.json
+func fileName(n int, method string, url string) string {
+	path := strings.SplitN(url, "?", 2)[0]
+	slug := strings.Trim(nonAlphanumeric.ReplaceAllString(path, "-"), "-")
+	if len(slug) > 80 {
+		slug = slug[:80]
+	}
+	return fmt.Sprintf("%04d-%s-%s.json", n, strings.ToLower(method), slug)
+}
+
+func discardLogger() logger.Logger {
+	l := log.New()
+	l.SetHandler(log.DiscardHandler())
+	return l
+}
@@ -0,0 +1,155 @@
+package apirecord
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const token = "5d1b0a6b2f.secret-token"
+
+// record sends the requests through a Recorder to a server that echoes them and returns the bodies
+func record(t *testing.T, dir string, requests ...*http.Request) []string {
+	progress := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/auth/key":
+			fmt.Fprint(w, "agent-token-0123456789")
+		case "/api/progress/1":
+			progress++
+			fmt.Fprintf(w, `{"status":"running","poll":%d}`, progress)
+		case "/api/binary":
+			w.Write([]byte{0xff, 0x00, 0xfe})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"message":"%s %s was not found, token %s"}`, r.Method, r.URL.Path, r.Header.Get("Authorization"))
+		}
+	}))
+	defer server.Close()
+
+	recorder, err := NewRecorder(&Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: recorder}
+	bodies := []string{}
+	for _, req := range requests {
+		req.URL.Scheme = "http"
+		req.URL.Host = strings.TrimPrefix(server.URL, "http://")
+		req.Header.Set("Authorization", token)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		bodies = append(bodies, string(data))
+	}
+	return bodies
+}
+
+func newRequest(method string, url string, body string) *http.Request {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if body == "" {
+		req.Body = nil
+	}
+	return req
+}
+
+func replay(t *testing.T, r *Replayer, method string, url string) string {
+	resp, err := (&http.Client{Transport: r}).Do(newRequest(method, "https://g.codefresh.io"+url, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(resp.Body)
+	return string(data)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apirecord")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	recorded := record(t, dir,
+		newRequest("POST", "/api/auth/key?name=agent&type=runtime", `{"token":"`+token+`"}`),
+		newRequest("GET", "/api/progress/1", ""),
+		newRequest("GET", "/api/progress/1", ""),
+		newRequest("GET", "/api/binary", ""),
+		newRequest("DELETE", "/api/pipelines/smoke-20191001", ""),
+	)
+	if recorded[0] != "agent-token-0123456789" {
+		t.Errorf("the recorder changed the response to %q", recorded[0])
+	}
+
+	files, _ := recordingFiles(dir)
+	if len(files) != 5 || filepath.Base(files[0]) != "0001-post-api-auth-key.json" {
+		t.Fatalf("unexpected recordings %v", files)
+	}
+	for _, f := range files {
+		data, _ := ioutil.ReadFile(f)
+		for _, secret := range []string{token, "agent-token-0123456789"} {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%s has the secret %s:\n%s", f, secret, data)
+			}
+		}
+	}
+
+	// a second run is numbered after the first one
+	record(t, dir, newRequest("GET", "/api/progress/1", ""))
+	files, _ = recordingFiles(dir)
+	if len(files) != 6 || !strings.HasPrefix(filepath.Base(files[5]), "0006-") {
+		t.Fatalf("unexpected recordings %v", files)
+	}
+
+	r, err := NewReplayer(&Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := replay(t, r, "POST", "/api/auth/key?name=agent&type=runtime"); body != Redacted {
+		t.Errorf("expected the redacted token, got %q", body)
+	}
+	for i, expected := range []string{`"poll":1`, `"poll":2`, `"poll":1`, `"poll":1`} {
+		if body := replay(t, r, "GET", "/api/progress/1"); !strings.Contains(body, expected) {
+			t.Errorf("poll %d: expected %s, got %s", i, expected, body)
+		}
+	}
+	// the sdk sends the query parameters in random order
+	if body := replay(t, r, "POST", "/api/auth/key?type=runtime&name=agent"); body != Redacted {
+		t.Errorf("expected the recording of the other parameter order, got %q", body)
+	}
+	if body := replay(t, r, "GET", "/api/binary"); body != string([]byte{0xff, 0x00, 0xfe}) {
+		t.Errorf("unexpected binary body %q", body)
+	}
+	if body := replay(t, r, "DELETE", "/api/pipelines/smoke-20191001"); !strings.Contains(body, "smoke-20191001 was not found, token REDACTED") {
+		t.Errorf("unexpected body %s", body)
+	}
+	for _, req := range []*http.Request{
+		newRequest("GET", "https://g.codefresh.io/api/contexts", ""),
+		newRequest("DELETE", "https://g.codefresh.io/api/pipelines/smoke-20191002", ""),
+		newRequest("GET", "https://g.codefresh.io/api/progress/1?poll=1", ""),
+	} {
+		expected := fmt.Sprintf("No recording for %s %s", req.Method, req.URL.RequestURI())
+		if _, err := (&http.Client{Transport: r}).Do(req); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestReplayEmptyDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apirecord")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := NewReplayer(&Options{Dir: dir}); err == nil {
+		t.Error("expected an error for a directory without recordings")
+	}
+}
@@ -0,0 +1,179 @@
+package apirecord
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/codefresh-io/venona/venonactl/pkg/logger"
+)
+
+// Recorder is an http.RoundTripper that writes every request and response it sends to a directory,
+// tokens are redacted from the recordings
+type Recorder struct {
+	dir       string
+	transport http.RoundTripper
+	logger    logger.Logger
+
+	mu      sync.Mutex
+	next    int
+	secrets map[string]bool
+}
+
+// NewRecorder creates the directory of the recordings, the recordings that are already in it are kept
+// and the new ones are numbered after them
+func NewRecorder(opt *Options) (*Recorder, error) {
+	if err := os.MkdirAll(opt.Dir, 0755); err != nil {
+		return nil, err
+	}
+	files, err := recordingFiles(opt.Dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{
+		dir:       opt.Dir,
+		transport: opt.Transport,
+		logger:    opt.Logger,
+		next:      1,
+		secrets:   map[string]bool{},
+	}
+	if len(files) > 0 {
+		r.next = sequence(files[len(files)-1]) + 1
+	}
+	if r.transport == nil {
+		r.transport = http.DefaultTransport
+	}
+	if r.logger == nil {
+		r.logger = discardLogger()
+	}
+	return r, nil
+}
+
+// RoundTrip sends the request with the transport of the recorder and records it,
+// failing to write the recording does not fail the request
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+	e := &Exchange{
+		Request: &Request{
+			Method: req.Method,
+			URL:    requestURL(req),
+			Header: req.Header.Clone(),
+			Body:   newBody(reqBody),
+		},
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		e.Error = err.Error()
+		r.write(e)
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	e.Response = &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       newBody(respBody),
+	}
+	r.write(e)
+	return resp, nil
+}
+
+// write redacts the exchange and writes it to the next recording file
+func (r *Recorder) write(e *Exchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.redact(e)
+	path := filepath.Join(r.dir, fileName(r.next, e.Request.Method, e.Request.URL))
+	r.next++
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(path, append(data, '\n'), 0600)
+	}
+	if err != nil {
+		r.logger.Warn("Failed to record API request", "Method", e.Request.Method, "URL", e.Request.URL, "Error", err)
+		return
+	}
+	r.logger.Debug("Recorded API request", "Method", e.Request.Method, "URL", e.Request.URL, "File", path)
+}
+
+// redact replaces the secret headers and the tokens they carried in every later recording,
+// the secrets are kept for the run since a token can be sent in one request and echoed by another
+func (r *Recorder) redact(e *Exchange) {
+	headers := []http.Header{e.Request.Header}
+	if e.Response != nil {
+		headers = append(headers, e.Response.Header)
+	}
+	for _, h := range headers {
+		for _, name := range secretHeaders {
+			values := h[http.CanonicalHeaderKey(name)]
+			for i, v := range values {
+				if tokenHeaders[http.CanonicalHeaderKey(name)] {
+					r.addSecret(v)
+				}
+				values[i] = Redacted
+			}
+		}
+	}
+	if e.Response != nil && e.Response.Body != nil {
+		for _, re := range secretResponses {
+			if re.MatchString(e.Request.key()) {
+				r.addSecret(e.Response.Body.Text)
+				e.Response.Body = &Body{Text: Redacted}
+			}
+		}
+	}
+
+	e.Request.URL = r.replace(e.Request.URL)
+	e.Error = r.replace(e.Error)
+	for _, h := range headers {
+		for name, values := range h {
+			for i := range values {
+				h[name][i] = r.replace(values[i])
+			}
+		}
+	}
+	if e.Request.Body != nil {
+		e.Request.Body.Text = r.replace(e.Request.Body.Text)
+	}
+	if e.Response != nil && e.Response.Body != nil {
+		e.Response.Body.Text = r.replace(e.Response.Body.Text)
+	}
+}
+
+func (r *Recorder) addSecret(value string) {
+	value = strings.TrimSpace(value)
+	// the scheme of "Bearer <token>" is not a secret
+	if i := strings.LastIndex(value, " "); i >= 0 {
+		value = value[i+1:]
+	}
+	// short values would redact unrelated text
+	if len(value) >= 8 && value != Redacted {
+		r.secrets[value] = true
+	}
+}
+
+func (r *Recorder) replace(s string) string {
+	for secret := range r.secrets {
+		s = strings.Replace(s, secret, Redacted, -1)
+	}
+	return s
+}
@@ -0,0 +1,101 @@
+package apirecord
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/codefresh-io/venona/venonactl/pkg/logger"
+)
+
+// Replayer is an http.RoundTripper that answers the requests from the recordings of a Recorder, no
+// request leaves the process. A request gets the first unused recording of the same method and url,
+// when they were all used the last one is repeated so polling ends on the recorded final state. A request
+// that was never recorded fails
+type Replayer struct {
+	dir       string
+	logger    logger.Logger
+	exchanges []*Exchange
+
+	mu   sync.Mutex
+	used []bool
+}
+
+// NewReplayer reads the recordings of the directory
+func NewReplayer(opt *Options) (*Replayer, error) {
+	exchanges, err := readExchanges(opt.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(exchanges) == 0 {
+		return nil, fmt.Errorf("No recordings in %s", opt.Dir)
+	}
+	r := &Replayer{
+		dir:       opt.Dir,
+		logger:    opt.Logger,
+		exchanges: exchanges,
+		used:      make([]bool, len(exchanges)),
+	}
+	if r.logger == nil {
+		r.logger = discardLogger()
+	}
+	return r, nil
+}
+
+// RoundTrip returns the recorded response of the request
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	request := &Request{Method: req.Method, URL: requestURL(req)}
+	e, err := r.find(request)
+	if err != nil {
+		return nil, err
+	}
+	r.logger.Debug("Replaying API request", "Method", request.Method, "URL", request.URL)
+	if e.Error != "" {
+		return nil, errors.New(e.Error)
+	}
+	body, err := e.Response.Body.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	header := e.Response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Response.StatusCode, http.StatusText(e.Response.StatusCode)),
+		StatusCode:    e.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Replayer) find(request *Request) (*Exchange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := -1
+	for i, e := range r.exchanges {
+		if e.Request.key() != request.key() {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return e, nil
+		}
+		last = i
+	}
+	if last < 0 {
+		return nil, fmt.Errorf("No recording for %s %s in %s", request.Method, request.URL, r.dir)
+	}
+	return r.exchanges[last], nil
+}
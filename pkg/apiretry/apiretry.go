@@ -0,0 +1,232 @@
+package apiretry
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/codefresh-io/venona/venonactl/pkg/logger"
+	log "github.com/inconshreveable/log15"
+)
+
+// Defaults of the Options
+const (
+	DefaultRetries       = 3
+	DefaultMinBackoff    = 500 * time.Millisecond
+	DefaultMaxBackoff    = 30 * time.Second
+	DefaultMaxRetryAfter = 2 * time.Minute
+)
+
+type (
+	// Options of the Transport
+	Options struct {
+		// Retries is the number of times a request is sent again after it failed, 0 disables the retries
+		Retries int
+		// Transport sends the requests, default is http.DefaultTransport
+		Transport http.RoundTripper
+		// MinBackoff is the wait before the first retry, it doubles on every retry up to MaxBackoff
+		MinBackoff time.Duration
+		MaxBackoff time.Duration
+		// MaxRetryAfter caps the wait the server asks for in the Retry-After header
+		MaxRetryAfter time.Duration
+		Logger        logger.Logger
+	}
+
+	// Transport is an http.RoundTripper that retries the requests that failed with a transient error:
+	// a connection error, 429, 502, 503 or 504. Requests that are not idempotent are retried only
+	// when the server did not process them, on 429 or when the connection was not established
+	Transport struct {
+		retries       int
+		transport     http.RoundTripper
+		minBackoff    time.Duration
+		maxBackoff    time.Duration
+		maxRetryAfter time.Duration
+		logger        logger.Logger
+		sleep         func(req *http.Request, d time.Duration) error
+	}
+)
+
+// retryStatuses are the responses of a server that is overloaded or restarting
+var retryStatuses = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// idempotentMethods can be sent twice with the effect of sending them once
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// notIdempotent are the Codefresh API calls that use an idempotent method but start something
+var notIdempotent = []*regexp.Regexp{
+	regexp.MustCompile(`^/api/builds/rebuild/`),
+}
+
+// New creates the Transport
+func New(opt *Options) *Transport {
+	t := &Transport{
+		retries:       opt.Retries,
+		transport:     opt.Transport,
+		minBackoff:    opt.MinBackoff,
+		maxBackoff:    opt.MaxBackoff,
+		maxRetryAfter: opt.MaxRetryAfter,
+		logger:        opt.Logger,
+		sleep:         sleep,
+	}
+	if t.transport == nil {
+		t.transport = http.DefaultTransport
+	}
+	if t.minBackoff == 0 {
+		t.minBackoff = DefaultMinBackoff
+	}
+	if t.maxBackoff == 0 {
+		t.maxBackoff = DefaultMaxBackoff
+	}
+	if t.maxRetryAfter == 0 {
+		t.maxRetryAfter = DefaultMaxRetryAfter
+	}
+	if t.logger == nil {
+		l := log.New()
+		l.SetHandler(log.DiscardHandler())
+		t.logger = l
+	}
+	return t
+}
+
+// RoundTrip sends the request until it succeeds, fails with an error that is not transient or
+// runs out of retries. The response of the last attempt is returned
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && t.retries > 0 {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	for attempt := 0; ; attempt++ {
+		if body != nil {
+			req = req.Clone(req.Context())
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		resp, err := t.transport.RoundTrip(req)
+		reason, wait := t.retryable(req, resp, err)
+		if reason == "" || attempt >= t.retries {
+			return resp, err
+		}
+
+		if wait == 0 {
+			wait = t.backoff(attempt)
+		}
+		t.logger.Warn("Retrying API request",
+			"Method", req.Method,
+			"URL", req.URL.Path,
+			"Reason", reason,
+			"Retry", fmt.Sprintf("%d/%d", attempt+1, t.retries),
+			"Wait", wait.Round(time.Millisecond))
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := t.sleep(req, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryable returns why the request should be sent again, empty when it should not, and how long
+// the server asked to wait
+func (t *Transport) retryable(req *http.Request, resp *http.Response, err error) (string, time.Duration) {
+	idempotent := isIdempotent(req)
+	if err != nil {
+		if idempotent || isDialError(err) {
+			return err.Error(), 0
+		}
+		return "", 0
+	}
+	if !retryStatuses[resp.StatusCode] {
+		return "", 0
+	}
+	if !idempotent && resp.StatusCode != http.StatusTooManyRequests {
+		return "", 0
+	}
+	return resp.Status, t.retryAfter(resp)
+}
+
+// retryAfter reads the Retry-After header, seconds or an http date
+func (t *Transport) retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = time.Until(date)
+	}
+	if wait < 0 {
+		return 0
+	}
+	if wait > t.maxRetryAfter {
+		return t.maxRetryAfter
+	}
+	return wait
+}
+
+// backoff doubles the wait on every attempt, with a random jitter of up to half of it
+// so clients that failed together do not retry together
+func (t *Transport) backoff(attempt int) time.Duration {
+	wait := t.minBackoff
+	for i := 0; i < attempt && wait < t.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > t.maxBackoff {
+		wait = t.maxBackoff
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+func isIdempotent(req *http.Request) bool {
+	if !idempotentMethods[req.Method] {
+		return false
+	}
+	for _, re := range notIdempotent {
+		if re.MatchString(req.URL.Path) {
+			return false
+		}
+	}
+	return true
+}
+
+// isDialError returns true when the connection was not established, so the server did not get the request
+func isDialError(err error) bool {
+	opErr := &net.OpError{}
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func sleep(req *http.Request, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
@@ -0,0 +1,130 @@
+package apiretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// server fails the first requests with the statuses, then answers 200 with the body of the request
+func server(statuses ...int) (*httptest.Server, *int) {
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= len(statuses) {
+			if statuses[requests-1] == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "7")
+			}
+			w.WriteHeader(statuses[requests-1])
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "ok %s", body)
+	})), &requests
+}
+
+func newTransport(retries int) (*Transport, *[]time.Duration) {
+	waits := &[]time.Duration{}
+	t := New(&Options{Retries: retries})
+	t.sleep = func(req *http.Request, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	return t, waits
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		statuses []int
+		status   int
+		requests int
+		waits    int
+	}{
+		{"get retried", "GET", "/api/pipelines", []int{502, 503}, 200, 3, 2},
+		{"not found is not retried", "GET", "/api/pipelines", []int{404}, 404, 1, 0},
+		{"retries run out", "GET", "/api/pipelines", []int{502, 502, 502, 502, 502}, 502, 4, 3},
+		{"post is not retried on 502", "POST", "/api/pipelines/run/p", []int{502}, 502, 1, 0},
+		{"post is retried on 429", "POST", "/api/pipelines/run/p", []int{429}, 200, 2, 1},
+		{"rebuild is not idempotent", "GET", "/api/builds/rebuild/1", []int{503}, 503, 1, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, requests := server(test.statuses...)
+			defer s.Close()
+			transport, waits := newTransport(3)
+			req, _ := http.NewRequest(test.method, s.URL+test.path, strings.NewReader("body"))
+			resp, err := (&http.Client{Transport: transport}).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != test.status || *requests != test.requests || len(*waits) != test.waits {
+				t.Errorf("expected %d after %d requests and %d waits, got %d after %d requests and %d waits",
+					test.status, test.requests, test.waits, resp.StatusCode, *requests, len(*waits))
+			}
+			if body, _ := ioutil.ReadAll(resp.Body); resp.StatusCode == 200 && string(body) != "ok body" {
+				t.Errorf("the retry did not send the body, got %q", body)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	s, _ := server(429)
+	defer s.Close()
+	transport, waits := newTransport(3)
+	if _, err := (&http.Client{Transport: transport}).Get(s.URL); err != nil {
+		t.Fatal(err)
+	}
+	if len(*waits) != 1 || (*waits)[0] != 7*time.Second {
+		t.Errorf("expected to wait the 7s of Retry-After, got %v", *waits)
+	}
+}
+
+func TestRetryAfterInterrupted(t *testing.T) {
+	s, requests := server(429)
+	defer s.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	req, _ := http.NewRequestWithContext(ctx, "GET", s.URL, nil)
+	start := time.Now()
+	if _, err := (&http.Client{Transport: New(&Options{Retries: 3})}).Do(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the wait of Retry-After to be cancelled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second || *requests != 1 {
+		t.Errorf("expected to stop waiting after 1 request, stopped after %v and %d requests", elapsed, *requests)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	transport := New(&Options{MinBackoff: time.Second, MaxBackoff: 5 * time.Second})
+	for attempt, max := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		for i := 0; i < 20; i++ {
+			if wait := transport.backoff(attempt); wait < max/2 || wait > max {
+				t.Fatalf("attempt %d: expected a wait between %v and %v, got %v", attempt, max/2, max, wait)
+			}
+		}
+	}
+}
+
+func TestRetryConnectionError(t *testing.T) {
+	s, _ := server()
+	url := s.URL
+	s.Close()
+	transport, waits := newTransport(2)
+	if _, err := (&http.Client{Transport: transport}).Post(url, "application/json", strings.NewReader("{}")); err == nil {
+		t.Fatal("expected a connection error")
+	}
+	if len(*waits) != 2 {
+		t.Errorf("expected a post that did not connect to be retried twice, got %d waits", len(*waits))
+	}
+}
@@ -0,0 +1,128 @@
+package cfapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+)
+
+type (
+	// Client calls the Codefresh API endpoints that are not covered by the go-sdk
+	Client struct {
+		host   string
+		token  string
+		client *http.Client
+	}
+
+	// Options to create a Client
+	Options struct {
+		Host  string
+		Token string
+	}
+
+	apiError struct {
+		Message string `json:"message"`
+		Code    string `json:"code"`
+	}
+
+	// ResponseError is a response with a 4xx or 5xx status code
+	ResponseError struct {
+		StatusCode int
+		Status     string
+		Message    string
+	}
+)
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Status, e.Message)
+}
+
+// IsNotFound returns true when err is a 404 response
+func IsNotFound(err error) bool {
+	e := &ResponseError{}
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+// New creates a Client, like the go-sdk it sends the requests through http.DefaultTransport
+func New(o *Options) *Client {
+	return &Client{
+		host:   strings.TrimSuffix(o.Host, "/"),
+		token:  o.Token,
+		client: &http.Client{},
+	}
+}
+
+// do sends the request and decodes the json response into target when it is not nil,
+// 401 and 403 responses are clierror.Auth errors and other failures are clierror.API errors.
+// A request that ctx stopped is a clierror.Timeout or clierror.Interrupted error
+func (c *Client) do(ctx context.Context, method string, path string, qs url.Values, body interface{}, target interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewBuffer(data)
+	}
+	u := c.host + path
+	if len(qs) > 0 {
+		u += "?" + qs.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctxErr := clierror.FromContext(ctx, fmt.Sprintf("%s %s", method, path)); ctxErr != nil {
+			return ctxErr
+		}
+		return clierror.Errorf(clierror.API, "%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return clierror.Errorf(clierror.API, "%s %s: %v", method, path, err)
+	}
+
+	if resp.StatusCode >= 400 {
+		msg := strings.TrimSpace(string(data))
+		e := &apiError{}
+		if json.Unmarshal(data, e) == nil && e.Message != "" {
+			msg = e.Message
+		}
+		kind := clierror.API
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			kind = clierror.Auth
+		}
+		return clierror.New(kind, fmt.Errorf("%s %s: %w", method, path, &ResponseError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Message:    msg,
+		}))
+	}
+
+	if target == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return clierror.Errorf(clierror.API, "%s %s: failed to decode response: %v", method, path, err)
+	}
+	return nil
+}
+
+func escape(name string) string {
+	return url.PathEscape(name)
+}
@@ -0,0 +1,32 @@
+package cfapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+)
+
+// TestRequestContext stops a request that does not get a response when its context is done
+func TestRequestContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	c := New(&Options{Host: server.URL, Token: "token"})
+
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelTimeout()
+	if _, err := c.GetWorkflow(timeout, "1"); clierror.KindOf(err) != clierror.Timeout {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+
+	interrupted, interrupt := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, interrupt)
+	if err := c.TerminateWorkflow(interrupted, "1"); clierror.KindOf(err) != clierror.Interrupted {
+		t.Errorf("expected an interrupted error, got %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package cfapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Pipeline as returned by the pipelines API, the json encoding keeps every field of the response
+	Pipeline struct {
+		Metadata PipelineMetadata `json:"metadata"`
+		Spec     PipelineSpec     `json:"spec"`
+
+		raw map[string]interface{}
+	}
+
+	getPipelinesResponse struct {
+		Docs  []*Pipeline `json:"docs"`
+		Count int         `json:"count"`
+	}
+
+	// PipelineMetadata of a pipeline
+	PipelineMetadata struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Project string `json:"project"`
+		Labels  struct {
+			Tags []string `json:"tags"`
+		} `json:"labels"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+
+	// PipelineSpec of a pipeline
+	PipelineSpec struct {
+		Triggers           []*Trigger `json:"triggers"`
+		RuntimeEnvironment struct {
+			Name string `json:"name"`
+		} `json:"runtimeEnvironment"`
+		Steps     map[string]interface{} `json:"steps"`
+		Variables []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"variables"`
+	}
+
+	// Trigger of a pipeline
+	Trigger struct {
+		Name     string   `json:"name"`
+		Type     string   `json:"type"`
+		Repo     string   `json:"repo"`
+		Events   []string `json:"events"`
+		Provider string   `json:"provider"`
+		Context  string   `json:"context"`
+	}
+
+	// RunOptions of a single pipeline run
+	RunOptions struct {
+		Branch             string
+		SHA                string
+		Trigger            string
+		Variables          map[string]string
+		NoCache            bool
+		ResetVolume        bool
+		RuntimeEnvironment string
+	}
+
+	// Branch of a git repository
+	Branch struct {
+		Name   string `json:"name"`
+		Commit struct {
+			Sha string `json:"sha"`
+		} `json:"commit"`
+	}
+)
+
+// TriggerTypeGit is the type of triggers that run on git events
+const TriggerTypeGit = "git"
+
+// GetPipeline returns the pipeline with the name (project/pipeline)
+func (c *Client) GetPipeline(ctx context.Context, name string) (*Pipeline, error) {
+	p := &Pipeline{}
+	err := c.do(ctx, "GET", fmt.Sprintf("/api/pipelines/%s", escape(name)), nil, nil, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ListPipelines returns a page of pipelines and the total number of pipelines
+func (c *Client) ListPipelines(ctx context.Context, limit int, offset int) ([]*Pipeline, int, error) {
+	r := &getPipelinesResponse{}
+	qs := url.Values{
+		"limit":  []string{strconv.Itoa(limit)},
+		"offset": []string{strconv.Itoa(offset)},
+	}
+	if err := c.do(ctx, "GET", "/api/pipelines", qs, nil, r); err != nil {
+		return nil, 0, err
+	}
+	return r.Docs, r.Count, nil
+}
+
+// CreatePipeline creates a pipeline from its json representation (metadata and spec)
+func (c *Client) CreatePipeline(ctx context.Context, pipeline map[string]interface{}) (*Pipeline, error) {
+	p := &Pipeline{}
+	err := c.do(ctx, "POST", "/api/pipelines", nil, pipeline, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ReplacePipeline updates the pipeline with the name from its json representation
+func (c *Client) ReplacePipeline(ctx context.Context, name string, pipeline map[string]interface{}) (*Pipeline, error) {
+	p := &Pipeline{}
+	err := c.do(ctx, "PUT", fmt.Sprintf("/api/pipelines/%s", escape(name)), nil, pipeline, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DeletePipeline deletes the pipeline with the name
+func (c *Client) DeletePipeline(ctx context.Context, name string) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/api/pipelines/%s", escape(name)), nil, nil, nil)
+}
+
+// RunPipeline starts a build and returns its id
+func (c *Client) RunPipeline(ctx context.Context, name string, opt *RunOptions) (string, error) {
+	body := map[string]interface{}{
+		"variables": opt.Variables,
+		"options": map[string]interface{}{
+			"noCache":     opt.NoCache,
+			"resetVolume": opt.ResetVolume,
+		},
+	}
+	if opt.Branch != "" {
+		body["branch"] = opt.Branch
+	}
+	if opt.SHA != "" {
+		body["sha"] = opt.SHA
+	}
+	if opt.Trigger != "" {
+		body["trigger"] = opt.Trigger
+	}
+	if opt.RuntimeEnvironment != "" {
+		body["runtimeEnvironment"] = opt.RuntimeEnvironment
+	}
+	var id string
+	err := c.do(ctx, "POST", fmt.Sprintf("/api/pipelines/run/%s", escape(name)), nil, body, &id)
+	return id, err
+}
+
+// GetBranch returns the branch of a repository (owner/name) through a git context
+func (c *Client) GetBranch(ctx context.Context, gitContext string, repo string, branch string) (*Branch, error) {
+	owner, name := repo, ""
+	if i := strings.Index(repo, "/"); i >= 0 {
+		owner, name = repo[:i], repo[i+1:]
+	}
+	b := &Branch{}
+	path := fmt.Sprintf("/api/repos/%s/%s/branch/%s", escape(owner), escape(name), escape(branch))
+	err := c.do(ctx, "GET", path, url.Values{"context": []string{gitContext}}, nil, b)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// GitTrigger returns the git trigger with the name, or the first git trigger when name is empty.
+// nil is returned when there is no such trigger
+func (p *Pipeline) GitTrigger(name string) *Trigger {
+	for _, t := range p.Spec.Triggers {
+		if t.Type != TriggerTypeGit {
+			continue
+		}
+		if name == "" || t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Raw returns the document as it was received
+func (p *Pipeline) Raw() map[string]interface{} {
+	return p.raw
+}
+
+// UnmarshalJSON decodes the typed fields and keeps the whole document
+func (p *Pipeline) UnmarshalJSON(data []byte) error {
+	type pipeline Pipeline
+	if err := json.Unmarshal(data, (*pipeline)(p)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &p.raw)
+}
+
+// MarshalJSON encodes the document as it was received
+func (p *Pipeline) MarshalJSON() ([]byte, error) {
+	if p.raw != nil {
+		return json.Marshal(p.raw)
+	}
+	type pipeline Pipeline
+	return json.Marshal((*pipeline)(p))
+}
@@ -0,0 +1,15 @@
+package cfapi
+
+import (
+	"strings"
+
+	"github.com/codefresh-io/go-sdk/pkg/codefresh"
+)
+
+// runtimeStatusOffline is the status message of a runtime environment whose agent does not report
+const runtimeStatusOffline = "offline"
+
+// IsOnline returns false when Codefresh reports the runtime environment as offline
+func IsOnline(re *codefresh.RuntimeEnvironment) bool {
+	return !strings.EqualFold(re.Status.Message, runtimeStatusOffline)
+}
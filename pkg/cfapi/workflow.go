@@ -0,0 +1,176 @@
+package cfapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Build statuses reported by Codefresh
+const (
+	StatusPending         = "pending"
+	StatusElected         = "elected"
+	StatusRunning         = "running"
+	StatusPendingApproval = "pending-approval"
+	StatusTerminating     = "terminating"
+	StatusSuccess         = "success"
+	StatusError           = "error"
+	StatusTerminated      = "terminated"
+	StatusDenied          = "denied"
+)
+
+type (
+	// Workflow is a build of a pipeline
+	Workflow struct {
+		ID           string    `json:"id"`
+		Status       string    `json:"status"`
+		PipelineName string    `json:"pipelineName"`
+		BranchName   string    `json:"branchName"`
+		Revision     string    `json:"revision"`
+		Trigger      string    `json:"trigger"`
+		Created      time.Time `json:"created"`
+		Started      time.Time `json:"started"`
+		Finished     time.Time `json:"finished"`
+		Progress     string    `json:"progress"`
+
+		RuntimeEnvironment struct {
+			Name string `json:"name"`
+		} `json:"runtimeEnvironment"`
+	}
+
+	// ListWorkflowsOptions are the filters of the builds API
+	ListWorkflowsOptions struct {
+		PipelineID string
+		Status     string
+		Branch     string
+		Limit      int
+		Page       int
+	}
+
+	listWorkflowsResponse struct {
+		Workflows struct {
+			Docs  []*Workflow `json:"docs"`
+			Total int         `json:"total"`
+		} `json:"workflows"`
+	}
+
+	// Progress holds the steps of a workflow
+	Progress struct {
+		ID     string  `json:"id"`
+		Status string  `json:"status"`
+		Steps  []*Step `json:"steps"`
+	}
+
+	// Step of a workflow, timestamps are unix seconds
+	Step struct {
+		Name              string   `json:"name"`
+		Title             string   `json:"title"`
+		Status            string   `json:"status"`
+		CreationTimeStamp int64    `json:"creationTimeStamp"`
+		FinishTimeStamp   int64    `json:"finishTimeStamp"`
+		Logs              []string `json:"logs"`
+	}
+)
+
+// IsTerminal returns true when a build with this status will not change anymore
+func IsTerminal(status string) bool {
+	switch status {
+	case StatusSuccess, StatusError, StatusTerminated, StatusDenied:
+		return true
+	}
+	return false
+}
+
+// GetWorkflow returns the build with the id
+func (c *Client) GetWorkflow(ctx context.Context, id string) (*Workflow, error) {
+	wf := &Workflow{}
+	err := c.do(ctx, "GET", fmt.Sprintf("/api/builds/%s", escape(id)), nil, nil, wf)
+	if err != nil {
+		return nil, err
+	}
+	return wf, nil
+}
+
+// ListWorkflows returns a page of builds, newest first, and the total number of builds that match the filters
+func (c *Client) ListWorkflows(ctx context.Context, opt *ListWorkflowsOptions) ([]*Workflow, int, error) {
+	qs := url.Values{
+		"limit": []string{strconv.Itoa(opt.Limit)},
+		"page":  []string{strconv.Itoa(opt.Page)},
+	}
+	if opt.PipelineID != "" {
+		qs.Set("pipeline", opt.PipelineID)
+	}
+	if opt.Status != "" {
+		qs.Set("status", opt.Status)
+	}
+	if opt.Branch != "" {
+		qs.Set("branchName", opt.Branch)
+	}
+	r := &listWorkflowsResponse{}
+	if err := c.do(ctx, "GET", "/api/workflow", qs, nil, r); err != nil {
+		return nil, 0, err
+	}
+	return r.Workflows.Docs, r.Workflows.Total, nil
+}
+
+// TerminateWorkflow stops a running build
+func (c *Client) TerminateWorkflow(ctx context.Context, id string) error {
+	return c.do(ctx, "POST", fmt.Sprintf("/api/builds/%s/terminate", escape(id)), nil, nil, nil)
+}
+
+// RestartWorkflow starts a new build with the parameters of the build and returns its id,
+// fromFailed continues from the step that failed instead of the first step
+func (c *Client) RestartWorkflow(ctx context.Context, id string, fromFailed bool) (string, error) {
+	qs := url.Values{}
+	if fromFailed {
+		qs.Set("fromFailed", "true")
+	}
+	var newID string
+	err := c.do(ctx, "GET", fmt.Sprintf("/api/builds/rebuild/%s", escape(id)), qs, nil, &newID)
+	return newID, err
+}
+
+// ApproveWorkflow approves or denies the pending approval step of a build
+func (c *Client) ApproveWorkflow(ctx context.Context, id string, approve bool) error {
+	action := "deny"
+	if approve {
+		action = "approve"
+	}
+	return c.do(ctx, "POST", fmt.Sprintf("/api/workflow/%s/pending-approval/%s", escape(id), action), nil, nil, nil)
+}
+
+// GetProgress returns the steps of a build, id is the Workflow.Progress
+func (c *Client) GetProgress(ctx context.Context, id string) (*Progress, error) {
+	p := &Progress{}
+	err := c.do(ctx, "GET", fmt.Sprintf("/api/progress/%s", escape(id)), nil, nil, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Duration of the step, until now when it is still running
+func (s *Step) Duration() time.Duration {
+	if s.CreationTimeStamp == 0 {
+		return 0
+	}
+	end := time.Now()
+	if s.FinishTimeStamp != 0 {
+		end = time.Unix(s.FinishTimeStamp, 0)
+	}
+	return end.Sub(time.Unix(s.CreationTimeStamp, 0))
+}
+
+// Duration of the build, until now when it is still running
+func (w *Workflow) Duration() time.Duration {
+	if w.Started.IsZero() {
+		return 0
+	}
+	end := time.Now()
+	if !w.Finished.IsZero() {
+		end = w.Finished
+	}
+	return end.Sub(w.Started)
+}
@@ -0,0 +1,148 @@
+package clierror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Kind categorizes an error, every kind has its own exit code
+type Kind int
+
+const (
+	// Unknown errors exit with 1
+	Unknown Kind = iota
+	// Usage errors are invalid flags or arguments
+	Usage
+	// Config errors are missing or invalid local configuration (.cfconfig, kubeconfig, .sharoncli.yaml)
+	Config
+	// Auth errors are rejected Codefresh credentials
+	Auth
+	// API errors are Codefresh API calls that could not be completed
+	API
+	// Provisioning errors are failures to create the cluster
+	Provisioning
+	// Install errors are failures to install the runtime on the cluster
+	Install
+	// PipelineFailed errors are builds that finished with a status other than success
+	PipelineFailed
+	// Timeout errors are operations that did not finish in time
+	Timeout
+	// Interrupted errors are commands stopped with SIGINT or SIGTERM
+	Interrupted
+	// Hook errors are hooks of .sharoncli.yaml that failed and are not optional
+	Hook
+)
+
+var kinds = map[Kind]struct {
+	name string
+	code int
+}{
+	Unknown:        {"unknown", 1},
+	Usage:          {"usage", 2},
+	Config:         {"config", 3},
+	Auth:           {"auth", 4},
+	API:            {"api", 5},
+	Provisioning:   {"provisioning", 6},
+	Install:        {"install", 7},
+	PipelineFailed: {"pipeline-failed", 8},
+	Timeout:        {"timeout", 9},
+	Interrupted:    {"interrupted", 130},
+	Hook:           {"hook", 10},
+}
+
+// Error is an error with a Kind
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+// ExitStatus is the failure of a command sharoncli ran in its place, like a plugin,
+// sharoncli exits with the same code
+type ExitStatus struct {
+	Command string
+	Code    int
+}
+
+func (k Kind) String() string {
+	return kinds[k].name
+}
+
+// ExitCode of the process when the command fails with an error of this kind
+func (k Kind) ExitCode() int {
+	return kinds[k].code
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the categorized error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the categorized error, for github.com/pkg/errors
+func (e *Error) Cause() error {
+	return e.Err
+}
+
+func (e *ExitStatus) Error() string {
+	return fmt.Sprintf("%s exited with code %d", e.Command, e.Code)
+}
+
+// New categorizes err, nil stays nil
+func New(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// Errorf creates a categorized error from a format
+func Errorf(kind Kind, format string, args ...interface{}) error {
+	return New(kind, fmt.Errorf(format, args...))
+}
+
+// FromContext categorizes the error of a done context, an expired deadline is a Timeout and a
+// cancellation is Interrupted. It returns nil while the context is not done
+func FromContext(ctx context.Context, operation string) error {
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return Errorf(Timeout, "%s did not finish in time", operation)
+	default:
+		return Errorf(Interrupted, "%s was interrupted", operation)
+	}
+}
+
+// KindOf returns the kind of the outermost categorized error in the chain of err
+func KindOf(err error) Kind {
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			return e.Kind
+		}
+		switch wrapper := err.(type) {
+		case interface{ Unwrap() error }:
+			err = wrapper.Unwrap()
+		case interface{ Cause() error }:
+			err = wrapper.Cause()
+		default:
+			return Unknown
+		}
+	}
+	return Unknown
+}
+
+// ExitCode returns the exit code for err, 0 when err is nil
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	status := &ExitStatus{}
+	if errors.As(err, &status) {
+		return status.Code
+	}
+	return KindOf(err).ExitCode()
+}
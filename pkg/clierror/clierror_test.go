@@ -0,0 +1,52 @@
+package clierror
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := map[error]int{
+		nil:                          0,
+		fmt.Errorf("plain"):          1,
+		Errorf(Config, "no context"): 3,
+		New(PipelineFailed, fmt.Errorf("build failed")):                8,
+		errors.Wrap(Errorf(Timeout, "timed out"), "waiting for build"): 9,
+		fmt.Errorf("running: %w", Errorf(Auth, "unauthorized")):        4,
+		New(Install, Errorf(API, "bad gateway")):                       7,
+		Errorf(Interrupted, "interrupted"):                             130,
+		Errorf(Hook, "hook failed"):                                    10,
+		fmt.Errorf("plugin: %w", &ExitStatus{"sharoncli-seed", 42}):    42,
+	}
+	for err, expected := range tests {
+		if code := ExitCode(err); code != expected {
+			t.Errorf("%v: expected exit code %d, got %d", err, expected, code)
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := FromContext(ctx, "waiting"); err != nil {
+		t.Errorf("expected nil for a context that is not done, got %v", err)
+	}
+	cancel()
+	if err := FromContext(ctx, "waiting"); KindOf(err) != Interrupted {
+		t.Errorf("expected an interrupted error, got %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	if err := FromContext(ctx, "waiting"); KindOf(err) != Timeout || err.Error() != "waiting did not finish in time" {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+}
+
+func TestNewNil(t *testing.T) {
+	if New(Config, nil) != nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Keys of .sharoncli.yaml that do not set flags
+const (
+	HooksKey    = "hooks"
+	ProfilesKey = "profiles"
+)
+
+type (
+	// Settings are the flag values of the config file or of a profile: the global flags and a section
+	// of flags by command, the command path joined with dashes like create-runtime
+	Settings struct {
+		Global   map[string]interface{}
+		Commands map[string]map[string]interface{}
+	}
+)
+
+// New creates empty settings
+func New() *Settings {
+	return &Settings{
+		Global:   map[string]interface{}{},
+		Commands: map[string]map[string]interface{}{},
+	}
+}
+
+// FromMap reads the settings of the keys of the config file or of a profile, a map is the section of a
+// command and any other value a global flag. The hooks and the profiles are skipped
+func FromMap(m map[string]interface{}) (*Settings, error) {
+	s := New()
+	for key, value := range m {
+		if key == HooksKey || key == ProfilesKey {
+			continue
+		}
+		section, ok := toMap(value)
+		if !ok {
+			s.Global[key] = value
+			continue
+		}
+		s.Commands[key] = map[string]interface{}{}
+		for flag, v := range section {
+			if _, ok := toMap(v); ok {
+				return nil, fmt.Errorf("%s.%s cannot be a map", key, flag)
+			}
+			s.Commands[key][flag] = v
+		}
+	}
+	return s, nil
+}
+
+// Parse reads the keys of a yaml or json config file. The keys keep their case, and the numbers are
+// int64 when they are integers and float64 otherwise
+func Parse(data []byte) (map[string]interface{}, error) {
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	file := map[string]interface{}{}
+	if err := decoder.Decode(&file); err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return map[string]interface{}{}, nil
+	}
+	return numbers(file).(map[string]interface{}), nil
+}
+
+// numbers replaces the json numbers of the decoded value
+func numbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = numbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = numbers(item)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return value
+}
+
+// Load returns the settings of the config file with the settings of the profile on top,
+// the settings of the file alone when profile is empty
+func Load(file map[string]interface{}, profile string) (*Settings, error) {
+	s, err := FromMap(file)
+	if err != nil {
+		return nil, err
+	}
+	if profile == "" {
+		return s, nil
+	}
+	values, ok := toMap(profileValues(file, profile))
+	if !ok {
+		return nil, fmt.Errorf("profile %q was not found, the profiles are %s", profile, strings.Join(Profiles(file), ", "))
+	}
+	p, err := FromMap(values)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %v", profile, err)
+	}
+	return s.Merge(p), nil
+}
+
+// Profiles returns the names of the profiles of the config file, sorted
+func Profiles(file map[string]interface{}) []string {
+	profiles, _ := toMap(file[ProfilesKey])
+	names := []string{}
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func profileValues(file map[string]interface{}, profile string) interface{} {
+	profiles, _ := toMap(file[ProfilesKey])
+	return profiles[profile]
+}
+
+// Path returns the parts of a dotted key of the config file, like create-runtime.kube-namespace
+func Path(key string) []string {
+	return strings.Split(key, ".")
+}
+
+// ProfilePath returns the parts of a dotted key in the profile, the name of the profile can have dots
+func ProfilePath(profile string, key string) []string {
+	return append([]string{ProfilesKey, profile}, Path(key)...)
+}
+
+// Get returns the value of the key with the parts of path
+func Get(file map[string]interface{}, path []string) (interface{}, bool) {
+	var value interface{} = file
+	for _, part := range path {
+		m, ok := toMap(value)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Set sets the value of the key with the parts of path, it adds the maps of the key that are missing
+func Set(file map[string]interface{}, path []string, value interface{}) error {
+	m := file
+	for i, part := range path[:len(path)-1] {
+		if m[part] == nil {
+			m[part] = map[string]interface{}{}
+		}
+		next, ok := toMap(m[part])
+		if !ok {
+			return fmt.Errorf("%s is not a map", strings.Join(path[:i+1], "."))
+		}
+		m[part] = next
+		m = next
+	}
+	m[path[len(path)-1]] = value
+	return nil
+}
+
+// Unset removes the key with the parts of path and the maps it leaves empty, it returns false when
+// the key is not set
+func Unset(file map[string]interface{}, path []string) bool {
+	if len(path) == 1 {
+		_, ok := file[path[0]]
+		delete(file, path[0])
+		return ok
+	}
+	m, ok := toMap(file[path[0]])
+	if !ok || !Unset(m, path[1:]) {
+		return false
+	}
+	if len(m) == 0 {
+		delete(file, path[0])
+	} else {
+		file[path[0]] = m
+	}
+	return true
+}
+
+// Merge returns new settings with the values of o on top of the values of s
+func (s *Settings) Merge(o *Settings) *Settings {
+	merged := New()
+	for _, from := range []*Settings{s, o} {
+		for flag, v := range from.Global {
+			merged.Global[flag] = v
+		}
+		for command, section := range from.Commands {
+			if merged.Commands[command] == nil {
+				merged.Commands[command] = map[string]interface{}{}
+			}
+			for flag, v := range section {
+				merged.Commands[command][flag] = v
+			}
+		}
+	}
+	return merged
+}
+
+// Map returns the settings in the layout of the config file
+func (s *Settings) Map() map[string]interface{} {
+	m := map[string]interface{}{}
+	for flag, v := range s.Global {
+		m[flag] = v
+	}
+	for command, section := range s.Commands {
+		m[command] = section
+	}
+	return m
+}
+
+// Values returns the values of a flag as the strings to set it with, one for every item of a list
+func Values(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return []string{}, nil
+	case []interface{}:
+		values := []string{}
+		for _, item := range v {
+			if _, ok := toMap(item); ok {
+				return nil, fmt.Errorf("a list of maps is not a flag value")
+			}
+			values = append(values, fmt.Sprint(item))
+		}
+		return values, nil
+	case []string:
+		return v, nil
+	}
+	if _, ok := toMap(value); ok {
+		return nil, fmt.Errorf("a map is not a flag value")
+	}
+	return []string{fmt.Sprint(value)}, nil
+}
+
+// toMap accepts the maps of the yaml and json decoders
+func toMap(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		converted := map[string]interface{}{}
+		for k, v := range m {
+			converted[fmt.Sprint(k)] = v
+		}
+		return converted, true
+	}
+	return nil, false
+}
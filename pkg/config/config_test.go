@@ -0,0 +1,132 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+const file = `
+codefresh-context: prod
+create-runtime:
+  kube-namespace: codefresh
+  storage-class: standard
+hooks:
+  post-install:
+    - command: notify
+profiles:
+  ci:
+    codefresh-context: ci
+    create-runtime:
+      storage-class: fast
+      config: kind.yaml
+    test-runtime:
+      name: [demo/hello, demo/build]
+  dev: {}
+`
+
+func parse(t *testing.T, data string) map[string]interface{} {
+	m := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(data), &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestLoad(t *testing.T) {
+	m := parse(t, file)
+	s, err := Load(m, "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := parse(t, `
+codefresh-context: ci
+create-runtime:
+  kube-namespace: codefresh
+  storage-class: fast
+  config: kind.yaml
+test-runtime:
+  name: [demo/hello, demo/build]
+`)
+	if !reflect.DeepEqual(s.Map(), expected) {
+		t.Errorf("expected %v, got %v", expected, s.Map())
+	}
+
+	if s, err = Load(m, ""); err != nil || s.Global["codefresh-context"] != "prod" || len(s.Commands) != 1 {
+		t.Errorf("expected the settings of the file without a profile, got %v, %v", s.Map(), err)
+	}
+	if _, err := Load(m, "prod"); err == nil || err.Error() != `profile "prod" was not found, the profiles are ci, dev` {
+		t.Errorf("expected a profile not found error, got %v", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	file, err := Parse([]byte(`
+output: table
+api-retries: 3
+profiles:
+  CI:
+    output: yaml
+  ci:
+    output: json
+  ci.v2:
+    test-runtime:
+      parallelism: 1.5
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"output":      "table",
+		"api-retries": int64(3),
+		"profiles": map[string]interface{}{
+			"CI":    map[string]interface{}{"output": "yaml"},
+			"ci":    map[string]interface{}{"output": "json"},
+			"ci.v2": map[string]interface{}{"test-runtime": map[string]interface{}{"parallelism": 1.5}},
+		},
+	}
+	if !reflect.DeepEqual(file, expected) {
+		t.Errorf("expected %v, got %v", expected, file)
+	}
+	if s, err := Load(file, "CI"); err != nil || s.Global["output"] != "yaml" {
+		t.Errorf("expected the output of the CI profile, got %v, %v", s, err)
+	}
+	if file, err := Parse([]byte(`{"Output": "json"}`)); err != nil || file["Output"] != "json" {
+		t.Errorf("expected the keys of a json file, got %v, %v", file, err)
+	}
+	if file, err := Parse(nil); err != nil || len(file) != 0 {
+		t.Errorf("expected no keys for an empty file, got %v, %v", file, err)
+	}
+	if _, err := Parse([]byte("output: [json")); err == nil {
+		t.Error("expected an error for invalid yaml")
+	}
+}
+
+func TestFromMapInvalid(t *testing.T) {
+	if _, err := FromMap(parse(t, "create-runtime:\n  wait:\n    seconds: 10\n")); err == nil {
+		t.Error("expected an error for a map in a command section")
+	}
+}
+
+func TestValues(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected []string
+	}{
+		{"codefresh", []string{"codefresh"}},
+		{true, []string{"true"}},
+		{float64(2), []string{"2"}},
+		{[]interface{}{"demo/hello", "demo/build"}, []string{"demo/hello", "demo/build"}},
+		{nil, []string{}},
+	}
+	for _, test := range tests {
+		values, err := Values(test.value)
+		if err != nil || !reflect.DeepEqual(values, test.expected) {
+			t.Errorf("%v: expected %v, got %v, %v", test.value, test.expected, values, err)
+		}
+	}
+	if _, err := Values(map[string]interface{}{"a": 1}); err == nil {
+		t.Error("expected an error for a map")
+	}
+}
@@ -0,0 +1,353 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProfileKey is the top-level key of .sharoncli.yaml that selects the default profile
+const ProfileKey = "profile"
+
+type (
+	// Schema is the flags the config file can set with their pflag type, like string, bool, int,
+	// duration or stringArray: the global flags and the flags of every command section
+	Schema struct {
+		Global   map[string]string
+		Commands map[string]map[string]string
+		// Checks validate the values of flags beyond their type, by key like output or create-runtime.cloud-provider
+		Checks map[string]func(value string) error
+	}
+
+	// Problem is a key of the config file the schema does not accept
+	Problem struct {
+		Key     string `json:"key"`
+		Message string `json:"message"`
+		// Unknown is true when the key is not a flag or a command, rather than a flag with an invalid value
+		Unknown bool `json:"-"`
+	}
+
+	// Problems is a list of problems that can be printed as a table
+	Problems []*Problem
+)
+
+// NewSchema creates an empty schema
+func NewSchema() *Schema {
+	return &Schema{
+		Global:   map[string]string{},
+		Commands: map[string]map[string]string{},
+		Checks:   map[string]func(value string) error{},
+	}
+}
+
+// Validate returns the keys of the config file that are not flags of the schema or have a value the flag
+// does not accept, sorted by key. The hooks are not validated
+func (s *Schema) Validate(file map[string]interface{}) Problems {
+	problems := Problems{}
+	for _, key := range sortedKeys(file) {
+		value := file[key]
+		switch key {
+		case HooksKey:
+			continue
+		case ProfileKey:
+			name, ok := value.(string)
+			if !ok {
+				problems.add(key, "the default profile must be a string")
+				continue
+			}
+			if _, ok := toMap(profileValues(file, name)); !ok {
+				problems.add(key, fmt.Sprintf("profile %q was not found", name))
+			}
+		case ProfilesKey:
+			profiles, ok := toMap(value)
+			if !ok {
+				problems.add(key, "the profiles must be a map of profile names")
+				continue
+			}
+			for _, name := range sortedKeys(profiles) {
+				values, ok := toMap(profiles[name])
+				if profiles[name] != nil && !ok {
+					problems.add(key+"."+name, "a profile must be a map of flags")
+					continue
+				}
+				for _, k := range sortedKeys(values) {
+					if k == HooksKey || k == ProfilesKey || k == ProfileKey {
+						problems.add(key+"."+name+"."+k, fmt.Sprintf("%s cannot be set by a profile", k))
+						continue
+					}
+					problems = append(problems, s.validateKey(key+"."+name+".", k, values[k])...)
+				}
+			}
+		default:
+			problems = append(problems, s.validateKey("", key, value)...)
+		}
+	}
+	return problems
+}
+
+// validateKey validates a global flag or a command section of the file or of a profile
+func (s *Schema) validateKey(prefix string, key string, value interface{}) Problems {
+	problems := Problems{}
+	section, isSection := toMap(value)
+	if !isSection {
+		typ, ok := s.Global[key]
+		if !ok {
+			problems.addUnknown(prefix+key, unknown("global flag", key, s.names()))
+			return problems
+		}
+		if err := s.checkValue(key, typ, value); err != nil {
+			problems.add(prefix+key, err.Error())
+		}
+		return problems
+	}
+	flags, ok := s.Commands[key]
+	if !ok {
+		problems.addUnknown(prefix+key, unknown("command", key, s.names()))
+		return problems
+	}
+	for _, flag := range sortedKeys(section) {
+		typ, ok := flags[flag]
+		if !ok {
+			names := []string{}
+			for name := range flags {
+				names = append(names, name)
+			}
+			problems.addUnknown(prefix+key+"."+flag, unknown("flag", flag, names))
+			continue
+		}
+		if err := s.checkValue(key+"."+flag, typ, section[flag]); err != nil {
+			problems.add(prefix+key+"."+flag, err.Error())
+		}
+	}
+	return problems
+}
+
+// FlagType returns the type of the flag of the key with the parts of path, like test-runtime.name or
+// profiles.ci.output, empty when the key is not a flag
+func (s *Schema) FlagType(path []string) string {
+	parts := path
+	if len(parts) > 2 && parts[0] == ProfilesKey {
+		parts = parts[2:]
+	}
+	switch len(parts) {
+	case 1:
+		return s.Global[parts[0]]
+	case 2:
+		return s.Commands[parts[0]][parts[1]]
+	}
+	return ""
+}
+
+// names are the global flags and the command sections
+func (s *Schema) names() []string {
+	names := []string{}
+	for name := range s.Global {
+		names = append(names, name)
+	}
+	for name := range s.Commands {
+		names = append(names, name)
+	}
+	return names
+}
+
+// ParseValue converts the arguments of a flag to the value the config file keeps, a list for the flags
+// that can be repeated
+func ParseValue(typ string, args []string) (interface{}, error) {
+	if isList(typ) {
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			values[i] = arg
+		}
+		return values, nil
+	}
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected one value, got %d", len(args))
+	}
+	if err := checkValue(typ, args[0]); err != nil {
+		return nil, err
+	}
+	switch {
+	case typ == "bool":
+		return strconv.ParseBool(args[0])
+	case strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint"):
+		return strconv.ParseInt(args[0], 0, 64)
+	}
+	return args[0], nil
+}
+
+// checkValue returns an error when the flag of the type does not accept the value
+func checkValue(typ string, value interface{}) error {
+	values, err := Values(value)
+	if err != nil {
+		return err
+	}
+	if _, ok := value.([]interface{}); ok && !isList(typ) {
+		return fmt.Errorf("expected %s, got a list", typ)
+	}
+	for _, v := range values {
+		switch {
+		case typ == "bool":
+			_, err = strconv.ParseBool(v)
+		case typ == "duration":
+			_, err = time.ParseDuration(v)
+		case strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint"):
+			_, err = strconv.ParseInt(v, 0, 64)
+		case strings.HasPrefix(typ, "float"):
+			_, err = strconv.ParseFloat(v, 64)
+		}
+		if err != nil {
+			return fmt.Errorf("expected %s, got %q", typ, v)
+		}
+	}
+	return nil
+}
+
+// checkValue returns an error when the flag of the key does not accept the value, because of its type or its check
+func (s *Schema) checkValue(key string, typ string, value interface{}) error {
+	if err := checkValue(typ, value); err != nil {
+		return err
+	}
+	check, ok := s.Checks[key]
+	if !ok {
+		return nil
+	}
+	values, _ := Values(value)
+	for _, v := range values {
+		if err := check(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isList is true for the types of the flags that can be repeated, like stringArray
+func isList(typ string) bool {
+	return strings.HasSuffix(typ, "Array") || strings.HasSuffix(typ, "Slice")
+}
+
+// unknown is the message of an unknown name, with the closest known name when there is one
+func unknown(kind string, name string, known []string) string {
+	message := fmt.Sprintf("unknown %s %s", kind, name)
+	if suggestion := closest(name, known); suggestion != "" {
+		message += fmt.Sprintf(", did you mean %s?", suggestion)
+	}
+	return message
+}
+
+// closest returns the known name that is at most 2 edits away from name, the first one in order of distance
+func closest(name string, known []string) string {
+	sort.Strings(known)
+	best, bestDistance := "", 3
+	for _, k := range known {
+		if d := distance(name, k); d < bestDistance {
+			best, bestDistance = k, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance of a and b
+func distance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (p *Problems) add(key string, message string) {
+	*p = append(*p, &Problem{Key: key, Message: message})
+}
+
+func (p *Problems) addUnknown(key string, message string) {
+	*p = append(*p, &Problem{Key: key, Message: message, Unknown: true})
+}
+
+// For returns the problems of the key, of its parents and of its children
+func (p Problems) For(key string) Problems {
+	problems := Problems{}
+	for _, problem := range p {
+		if problem.Key == key || strings.HasPrefix(key, problem.Key+".") || strings.HasPrefix(problem.Key, key+".") {
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+// Applies is true when a command reads the key of a problem: the profile key, a global flag or a flag of
+// the section of the command, in the file or in the selected profile. The hooks are validated by the
+// commands that run them
+func Applies(file map[string]interface{}, key string, section string, profile string) bool {
+	if key == ProfilesKey || strings.HasPrefix(key, ProfilesKey+".") {
+		// the names of the profiles can have dots, the key is matched with the selected one
+		prefix := ProfilesKey + "." + profile
+		switch {
+		case profile == "":
+			return false
+		case key == ProfilesKey || key == prefix:
+			return true
+		case !strings.HasPrefix(key, prefix+"."):
+			return false
+		}
+		file, _ = toMap(profileValues(file, profile))
+		key = strings.TrimPrefix(key, prefix+".")
+	}
+	parts := Path(key)
+	switch {
+	case parts[0] == ProfileKey:
+		return true
+	case parts[0] == HooksKey:
+		return false
+	case len(parts) > 1:
+		return parts[0] == section
+	}
+	_, isSection := toMap(file[parts[0]])
+	return !isSection || parts[0] == section
+}
+
+// Header of the problems table
+func (p Problems) Header(wide bool) []string {
+	return []string{"KEY", "PROBLEM"}
+}
+
+// Rows of the problems table
+func (p Problems) Rows(wide bool) [][]string {
+	rows := [][]string{}
+	for _, problem := range p {
+		rows = append(rows, []string{problem.Key, problem.Message})
+	}
+	return rows
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func testSchema() *Schema {
+	s := NewSchema()
+	s.Global["output"] = "string"
+	s.Global["timeout"] = "duration"
+	s.Commands["create-runtime"] = map[string]string{"wait": "duration", "retain": "bool", "kube-namespace": "string"}
+	s.Commands["test-runtime"] = map[string]string{"name": "stringArray", "parallelism": "int"}
+	s.Checks["output"] = func(value string) error {
+		if value != "json" && value != "table" {
+			return fmt.Errorf("unknown output format %s", value)
+		}
+		return nil
+	}
+	return s
+}
+
+func TestValidate(t *testing.T) {
+	file := parse(t, `
+outptu: json
+timeout: 10m
+profile: ci
+create-runtime:
+  wait: 10
+  retain: yes-please
+  kube-namespace: codefresh
+crate-runtime:
+  wait: 10s
+test-runtime:
+  name: demo/hello
+  parallelism: [1, 2]
+hooks:
+  post-install: []
+profiles:
+  ci:
+    output: [json]
+    test-runtime:
+      name: [demo/hello, demo/build]
+      parallelizm: 2
+    hooks: {}
+`)
+	expected := Problems{
+		{Key: "crate-runtime", Message: "unknown command crate-runtime, did you mean create-runtime?", Unknown: true},
+		{Key: "create-runtime.retain", Message: `expected bool, got "yes-please"`},
+		{Key: "create-runtime.wait", Message: `expected duration, got "10"`},
+		{Key: "outptu", Message: "unknown global flag outptu, did you mean output?", Unknown: true},
+		{Key: "profiles.ci.hooks", Message: "hooks cannot be set by a profile"},
+		{Key: "profiles.ci.output", Message: "expected string, got a list"},
+		{Key: "profiles.ci.test-runtime.parallelizm", Message: "unknown flag parallelizm, did you mean parallelism?", Unknown: true},
+		{Key: "test-runtime.parallelism", Message: "expected int, got a list"},
+	}
+	problems := testSchema().Validate(file)
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("expected %v, got %v", expected.Rows(false), problems.Rows(false))
+	}
+
+	if problems := testSchema().Validate(parse(t, "profile: nope\n")); len(problems) != 1 || problems[0].Message != `profile "nope" was not found` {
+		t.Errorf("expected an unknown default profile, got %v", problems.Rows(false))
+	}
+	if problems := testSchema().Validate(parse(t, "profiles:\n  dev: {}\n  ci:\n")); len(problems) != 0 {
+		t.Errorf("expected empty profiles to be valid, got %v", problems.Rows(false))
+	}
+	problems = testSchema().Validate(parse(t, "output: xml\nprofiles:\n  ci:\n    output: json\n"))
+	if len(problems) != 1 || problems[0].Key != "output" || problems[0].Message != "unknown output format xml" || problems[0].Unknown {
+		t.Errorf("expected the check of output to fail, got %v", problems.Rows(false))
+	}
+}
+
+func TestApplies(t *testing.T) {
+	file := parse(t, `
+outptu: json
+crate-runtime:
+  wait: 10s
+test-runtime:
+  parallelism: [1, 2]
+profiles:
+  ci:
+    output: [json]
+    test-runtime:
+      parallelizm: 2
+    hooks: {}
+  dev: []
+  ci.v2:
+    output: [json]
+`)
+	tests := []struct {
+		key      string
+		section  string
+		profile  string
+		expected bool
+	}{
+		{"outptu", "create-runtime", "", true},
+		{"crate-runtime", "create-runtime", "", false},
+		{"test-runtime.parallelism", "test-runtime", "", true},
+		{"test-runtime.parallelism", "create-runtime", "", false},
+		{"profile", "create-runtime", "", true},
+		{"hooks", "create-runtime", "", false},
+		{"profiles.ci.output", "create-runtime", "ci", true},
+		{"profiles.ci.output", "create-runtime", "", false},
+		{"profiles.ci.output", "create-runtime", "dev", false},
+		{"profiles.ci.test-runtime.parallelizm", "test-runtime", "ci", true},
+		{"profiles.ci.test-runtime.parallelizm", "create-runtime", "ci", false},
+		{"profiles.ci.hooks", "test-runtime", "ci", false},
+		{"profiles.dev", "test-runtime", "dev", true},
+		{"profiles.dev", "test-runtime", "ci", false},
+		{"profiles", "test-runtime", "ci", true},
+		{"profiles", "test-runtime", "", false},
+		{"profiles.ci.v2.output", "test-runtime", "ci.v2", true},
+		{"profiles.ci.v2.output", "test-runtime", "ci", false},
+		{"profiles.ci.output", "test-runtime", "ci.v2", false},
+	}
+	for _, test := range tests {
+		if applies := Applies(file, test.key, test.section, test.profile); applies != test.expected {
+			t.Errorf("%s for %s with profile %q: expected %v, got %v", test.key, test.section, test.profile, test.expected, applies)
+		}
+	}
+}
+
+func TestFlagType(t *testing.T) {
+	s := testSchema()
+	for key, expected := range map[string]string{
+		"timeout":                          "duration",
+		"test-runtime.name":                "stringArray",
+		"profiles.ci.create-runtime.wait":  "duration",
+		"profiles.ci.output":               "string",
+		"create-runtime.missing":           "",
+		"create-runtime.wait.seconds":      "",
+		"profiles.ci.test-runtime.missing": "",
+	} {
+		if typ := s.FlagType(Path(key)); typ != expected {
+			t.Errorf("%s: expected %q, got %q", key, expected, typ)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		typ      string
+		args     []string
+		expected interface{}
+	}{
+		{"string", []string{"codefresh"}, "codefresh"},
+		{"bool", []string{"true"}, true},
+		{"int", []string{"4"}, int64(4)},
+		{"duration", []string{"10m"}, "10m"},
+		{"stringArray", []string{"demo/hello", "demo/build"}, []interface{}{"demo/hello", "demo/build"}},
+	}
+	for _, test := range tests {
+		value, err := ParseValue(test.typ, test.args)
+		if err != nil || !reflect.DeepEqual(value, test.expected) {
+			t.Errorf("%s %v: expected %v, got %v, %v", test.typ, test.args, test.expected, value, err)
+		}
+	}
+	for _, test := range []struct {
+		typ  string
+		args []string
+	}{{"int", []string{"two"}}, {"duration", []string{"10"}}, {"string", []string{"a", "b"}}} {
+		if _, err := ParseValue(test.typ, test.args); err == nil {
+			t.Errorf("%s %v: expected an error", test.typ, test.args)
+		}
+	}
+}
+
+func TestSetUnset(t *testing.T) {
+	file := parse(t, "output: json\ncreate-runtime:\n  wait: 10s\n")
+	if err := Set(file, Path("profiles.ci.test-runtime.parallelism"), 2); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := Get(file, Path("profiles.ci.test-runtime.parallelism")); !ok || value != 2 {
+		t.Errorf("expected 2, got %v", value)
+	}
+	if err := Set(file, ProfilePath("ci.v2", "output"), "yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := Get(file, ProfilePath("ci.v2", "output")); !ok || value != "yaml" || !reflect.DeepEqual(Profiles(file), []string{"ci", "ci.v2"}) {
+		t.Errorf("expected the output of the ci.v2 profile, got %v and the profiles %v", value, Profiles(file))
+	}
+	if err := Set(file, Path("output.format"), "json"); err == nil {
+		t.Error("expected an error for a key under a value")
+	}
+	if !Unset(file, Path("create-runtime.wait")) || Unset(file, Path("create-runtime.wait")) {
+		t.Error("expected create-runtime.wait to be removed once")
+	}
+	if !Unset(file, Path("profiles.ci.test-runtime.parallelism")) || !Unset(file, ProfilePath("ci.v2", "output")) {
+		t.Error("expected the parallelism of the ci profile and the output of the ci.v2 profile to be removed")
+	}
+	expected := map[string]interface{}{"output": "json"}
+	if !reflect.DeepEqual(file, expected) {
+		t.Errorf("expected the empty maps to be removed, got %v", file)
+	}
+}
@@ -0,0 +1,37 @@
+package diff
+
+import (
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	expected := `--- live
++++ file
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -11,3 +11,4 @@
+ k
+ l
+ m
++n
+`
+	if got := Unified(a, b, "live", "file"); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestUnifiedEqual(t *testing.T) {
+	if got := Unified("a\n", "a\n", "live", "file"); got != "" {
+		t.Errorf("expected no diff, got %q", got)
+	}
+	if got := Unified("", "a\n", "live", "file"); got != "--- live\n+++ file\n@@ -0,0 +1,1 @@\n+a\n" {
+		t.Errorf("unexpected diff for a new file %q", got)
+	}
+}
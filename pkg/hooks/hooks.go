@@ -0,0 +1,178 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codefresh-io/venona/venonactl/pkg/logger"
+	log "github.com/inconshreveable/log15"
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+)
+
+// Events the hooks run on
+const (
+	// PreCreate runs before create runtime creates the cluster
+	PreCreate = "pre-create"
+	// PostCluster runs once the cluster is created and its nodes are ready
+	PostCluster = "post-cluster"
+	// PostInstall runs once the runtime is installed on the cluster
+	PostInstall = "post-install"
+	// PreDelete runs before the cluster of an interrupted runtime is deleted
+	PreDelete = "pre-delete"
+	// PostTest runs once the builds of test runtime finished
+	PostTest = "post-test"
+)
+
+// Events are all the events, in the order they happen
+var Events = []string{PreCreate, PostCluster, PostInstall, PreDelete, PostTest}
+
+type (
+	// Hook is an executable run on an event
+	Hook struct {
+		// Name identifies the hook in the log, default is the command
+		Name    string   `mapstructure:"name"`
+		Command string   `mapstructure:"command"`
+		Args    []string `mapstructure:"args"`
+		// Optional hooks only log a warning when they fail
+		Optional bool `mapstructure:"optional"`
+		// Timeout kills the hook, default is no limit
+		Timeout time.Duration `mapstructure:"timeout"`
+	}
+
+	// Config is the hooks section of .sharoncli.yaml, the hooks of every event
+	Config map[string][]*Hook
+
+	// Runtime is the runtime the hooks run for, it is passed to them in the environment
+	Runtime struct {
+		KubeConfig         string
+		KubeContext        string
+		Namespace          string
+		RuntimeEnvironment string
+		// Result of the builds for post-test: passed or failed
+		Result string
+	}
+
+	// Options of the Runner
+	Options struct {
+		Config Config
+		// Output is where the output of the hooks is written, default is os.Stderr
+		Output io.Writer
+		Logger logger.Logger
+	}
+
+	// Runner runs the hooks of an event
+	Runner struct {
+		config Config
+		output io.Writer
+		logger logger.Logger
+	}
+)
+
+// New creates a Runner, it fails with a clierror.Config error for unknown events and hooks without a command
+func New(opt *Options) (*Runner, error) {
+	for event, hooks := range opt.Config {
+		if !isEvent(event) {
+			return nil, clierror.Errorf(clierror.Config, "Unknown hook event %q, the events are %s", event, strings.Join(Events, ", "))
+		}
+		for i, h := range hooks {
+			if h == nil || h.Command == "" {
+				return nil, clierror.Errorf(clierror.Config, "Hook %d of %s has no command", i+1, event)
+			}
+		}
+	}
+	r := &Runner{
+		config: opt.Config,
+		output: opt.Output,
+		logger: opt.Logger,
+	}
+	if r.output == nil {
+		r.output = os.Stderr
+	}
+	if r.logger == nil {
+		l := log.New()
+		l.SetHandler(log.DiscardHandler())
+		r.logger = l
+	}
+	return r, nil
+}
+
+// Run runs the hooks of the event one after the other, it stops at the first hook that failed and
+// is not optional and returns a clierror.Hook error
+func (r *Runner) Run(ctx context.Context, event string, rt *Runtime) error {
+	for _, h := range r.config[event] {
+		name := h.Name
+		if name == "" {
+			name = h.Command
+		}
+		r.logger.Info("Running hook", "Event", event, "Hook", name)
+		err := r.run(ctx, event, h, rt)
+		if err == nil {
+			continue
+		}
+		if h.Optional {
+			r.logger.Warn("Optional hook failed", "Event", event, "Hook", name, "Error", err)
+			continue
+		}
+		if ctxErr := clierror.FromContext(ctx, fmt.Sprintf("Hook %s of %s", name, event)); ctxErr != nil {
+			return ctxErr
+		}
+		return clierror.Errorf(clierror.Hook, "Hook %s of %s failed: %v", name, event, err)
+	}
+	return nil
+}
+
+func (r *Runner) run(ctx context.Context, event string, h *Hook, rt *Runtime) error {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, h.Command, h.Args...)
+	cmd.Env = append(os.Environ(), environment(event, rt)...)
+	// stdout is kept for the output of sharoncli
+	cmd.Stdout = r.output
+	cmd.Stderr = r.output
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded && h.Timeout > 0 {
+		return fmt.Errorf("did not finish in %s", h.Timeout)
+	}
+	return err
+}
+
+// environment is the environment of the hooks of the event, KUBECONFIG is set so kubectl uses the cluster of the runtime
+func environment(event string, rt *Runtime) []string {
+	env := map[string]string{
+		"SHARONCLI_HOOK_EVENT":          event,
+		"SHARONCLI_KUBECONFIG":          rt.KubeConfig,
+		"SHARONCLI_KUBE_CONTEXT":        rt.KubeContext,
+		"SHARONCLI_NAMESPACE":           rt.Namespace,
+		"SHARONCLI_RUNTIME_ENVIRONMENT": rt.RuntimeEnvironment,
+	}
+	if rt.KubeConfig != "" {
+		env["KUBECONFIG"] = rt.KubeConfig
+	}
+	if rt.Result != "" {
+		env["SHARONCLI_TEST_RESULT"] = rt.Result
+	}
+	vars := []string{}
+	for k, v := range env {
+		vars = append(vars, k+"="+v)
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+func isEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
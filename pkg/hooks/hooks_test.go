@@ -0,0 +1,78 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+)
+
+func newRunner(t *testing.T, config Config) (*Runner, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	r, err := New(&Options{Config: config, Output: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, out
+}
+
+func script(s string) *Hook {
+	return &Hook{Command: "sh", Args: []string{"-c", s}}
+}
+
+func TestRunEnvironment(t *testing.T) {
+	r, out := newRunner(t, Config{
+		PostCluster: {script(`echo "$SHARONCLI_HOOK_EVENT $KUBECONFIG $SHARONCLI_KUBE_CONTEXT $SHARONCLI_NAMESPACE $SHARONCLI_RUNTIME_ENVIRONMENT"`)},
+	})
+	err := r.Run(context.Background(), PostCluster, &Runtime{
+		KubeConfig:         "/home/kind-config-kind",
+		KubeContext:        "kubernetes-admin@kind",
+		Namespace:          "codefresh",
+		RuntimeEnvironment: "kubernetes-admin@kind/codefresh",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "post-cluster /home/kind-config-kind kubernetes-admin@kind codefresh kubernetes-admin@kind/codefresh\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	optional := script("echo optional; exit 1")
+	optional.Optional = true
+	r, out := newRunner(t, Config{
+		PostInstall: {optional, script("echo required; exit 2"), script("echo skipped")},
+	})
+	err := r.Run(context.Background(), PostInstall, &Runtime{})
+	if clierror.KindOf(err) != clierror.Hook || !strings.Contains(err.Error(), "exit status 2") {
+		t.Errorf("expected the hook error of the second hook, got %v", err)
+	}
+	if out.String() != "optional\nrequired\n" {
+		t.Errorf("expected the hooks to stop at the failed one, got %q", out.String())
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	h := &Hook{Name: "sleep", Command: "sleep", Args: []string{"5"}, Timeout: 100 * time.Millisecond}
+	r, _ := newRunner(t, Config{PreDelete: {h}})
+	err := r.Run(context.Background(), PreDelete, &Runtime{})
+	if clierror.KindOf(err) != clierror.Hook || !strings.Contains(err.Error(), "did not finish in 100ms") {
+		t.Errorf("expected a timeout of the hook, got %v", err)
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	for name, config := range map[string]Config{
+		"unknown event": {"post-create": {script("true")}},
+		"no command":    {PreCreate: {{Name: "empty"}}},
+	} {
+		if _, err := New(&Options{Config: config}); clierror.KindOf(err) != clierror.Config {
+			t.Errorf("%s: expected a config error, got %v", name, err)
+		}
+	}
+}
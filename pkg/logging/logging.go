@@ -0,0 +1,195 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codefresh-io/venona/venonactl/pkg/logger"
+	log "github.com/inconshreveable/log15"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// FormatText prints human readable logfmt lines
+	FormatText = "text"
+	// FormatJSON prints one json object per line
+	FormatJSON = "json"
+
+	timestampLayout = "20060102-150405.000"
+	logFileSuffix   = ".json"
+
+	// Redacted replaces the values of the secret keys of the records
+	Redacted = "REDACTED"
+)
+
+// secretKeys are the parts of the record keys whose values are never written, in any case
+var secretKeys = []string{"token", "password", "secret", "authorization"}
+
+// Options configures the logger of a single run
+type Options struct {
+	// Command is attached to every record and used in the default file name
+	Command string
+	// Level is the minimal level written to the console (debug, info, warn, error, crit)
+	Level string
+	// Format of the console output, text or json
+	Format string
+	// Output is where console records go, defaults to os.Stdout
+	Output io.Writer
+	// File is an explicit path of the run log, when empty a new file is created in Dir
+	File string
+	// Dir holds the per-run logs, files older than MaxAge or beyond MaxFiles are removed
+	Dir      string
+	MaxFiles int
+	MaxAge   time.Duration
+}
+
+// New creates the logger of the run, the run log always gets every record as json
+// and the records of libraries that use logrus (kind) are forwarded to it as well
+func New(o *Options) (logger.Logger, error) {
+	lvl, err := log.LvlFromString(o.Level)
+	if err != nil {
+		return nil, fmt.Errorf("Unknown log level %q", o.Level)
+	}
+	var consoleFormat log.Format
+	switch o.Format {
+	case "", FormatText:
+		consoleFormat = log.LogfmtFormat()
+	case FormatJSON:
+		consoleFormat = log.JsonFormat()
+	default:
+		return nil, fmt.Errorf("Unknown log format %q, supported formats are %s and %s", o.Format, FormatText, FormatJSON)
+	}
+	out := o.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	path := o.File
+	if path == "" && o.Dir != "" {
+		if err := os.MkdirAll(o.Dir, 0755); err != nil {
+			return nil, err
+		}
+		path = filepath.Join(o.Dir, fmt.Sprintf("%s-%s%s", time.Now().Format(timestampLayout), fileName(o.Command), logFileSuffix))
+	}
+
+	l := log.New(log.Ctx{
+		"Command": o.Command,
+	})
+	handlers := []log.Handler{
+		log.LvlFilterHandler(lvl, log.StreamHandler(out, consoleFormat)),
+	}
+	if path != "" {
+		fileHandler, err := log.FileHandler(path, log.JsonFormat())
+		if err != nil {
+			return nil, err
+		}
+		handlers = append(handlers, log.CallerFileHandler(log.CallerFuncHandler(fileHandler)))
+	}
+	l.SetHandler(redactHandler(log.MultiHandler(handlers...)))
+
+	if o.File == "" && o.Dir != "" {
+		if err := prune(o.Dir, path, o.MaxFiles, o.MaxAge); err != nil {
+			l.Warn("Failed to remove old log files", "Dir", o.Dir, "Error", err)
+		}
+	}
+
+	logrus.SetOutput(ioutil.Discard)
+	logrus.SetLevel(logrus.DebugLevel)
+	logrus.StandardLogger().ReplaceHooks(logrus.LevelHooks{})
+	logrus.AddHook(&logrusHook{l})
+
+	if path != "" {
+		l.Debug("Writing run log", "File", path)
+	}
+	return l, nil
+}
+
+// redactHandler replaces the values of the secret keys before the records reach h, the run log
+// keeps the debug records and is kept for days
+func redactHandler(h log.Handler) log.Handler {
+	return log.FuncHandler(func(r *log.Record) error {
+		for i := 0; i+1 < len(r.Ctx); i += 2 {
+			if key, ok := r.Ctx[i].(string); ok && isSecret(key) {
+				r.Ctx[i+1] = Redacted
+			}
+		}
+		return h.Log(r)
+	})
+}
+
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range secretKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// prune removes the logs that exceeds the retention limits, current is never removed
+func prune(dir string, current string, maxFiles int, maxAge time.Duration) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	logs := []os.FileInfo{}
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), logFileSuffix) && filepath.Join(dir, f.Name()) != current {
+			logs = append(logs, f)
+		}
+	}
+	// newest first, the names start with the creation time
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].Name() > logs[j].Name()
+	})
+	for i, f := range logs {
+		tooMany := maxFiles > 0 && i+1 >= maxFiles
+		tooOld := maxAge > 0 && time.Since(f.ModTime()) > maxAge
+		if tooMany || tooOld {
+			if err := os.Remove(filepath.Join(dir, f.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func fileName(command string) string {
+	return strings.ToLower(strings.Join(strings.Fields(command), "-"))
+}
+
+// logrusHook forwards logrus entries to the run logger
+type logrusHook struct {
+	logger log.Logger
+}
+
+func (h *logrusHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *logrusHook) Fire(e *logrus.Entry) error {
+	ctx := []interface{}{}
+	for k, v := range e.Data {
+		ctx = append(ctx, k, v)
+	}
+	switch e.Level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		h.logger.Crit(e.Message, ctx...)
+	case logrus.ErrorLevel:
+		h.logger.Error(e.Message, ctx...)
+	case logrus.WarnLevel:
+		h.logger.Warn(e.Message, ctx...)
+	case logrus.InfoLevel:
+		h.logger.Info(e.Message, ctx...)
+	default:
+		h.logger.Debug(e.Message, ctx...)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package logging
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestSecretsAreRedacted(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "run.json")
+	console := &bytes.Buffer{}
+	l, err := New(&Options{Command: "test runtime", Level: "debug", Output: console, File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Debug("Creating codefresh client", "host", "https://g.codefresh.io", "token", "secret-token-1")
+	l.Info("Using context", "Token", "secret-token-2", "API-Token", "secret-token-3")
+	logrus.WithField("authorization", "Bearer secret-token-4").Debug("from kind")
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, output := range map[string]string{"file": string(data), "console": console.String()} {
+		if strings.Contains(output, "secret-token") {
+			t.Errorf("expected no token in the %s log, got %s", name, output)
+		}
+		if !strings.Contains(output, Redacted) || !strings.Contains(output, "https://g.codefresh.io") {
+			t.Errorf("expected the token to be redacted and the other values kept in the %s log, got %s", name, output)
+		}
+	}
+}
+
+func writeLogs(t *testing.T, dir string, names ...string) {
+	for _, name := range names {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("{}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func remaining(t *testing.T, dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	return names
+}
+
+func TestPruneCount(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	writeLogs(t, dir,
+		"20261001-100000.000-test-runtime.json",
+		"20261002-100000.000-test-runtime.json",
+		"20261003-100000.000-create-runtime.json",
+		"20261004-100000.000-test-runtime.json",
+		"20261005-100000.000-test-runtime.json",
+		"notes.txt",
+	)
+	current := filepath.Join(dir, "20261005-100000.000-test-runtime.json")
+	if err := prune(dir, current, 3, 0); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"20261003-100000.000-create-runtime.json",
+		"20261004-100000.000-test-runtime.json",
+		"20261005-100000.000-test-runtime.json",
+		"notes.txt",
+	}
+	if names := remaining(t, dir); strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	if err := prune(dir, current, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if names := remaining(t, dir); len(names) != len(expected) {
+		t.Errorf("expected no limit to keep %v, got %v", expected, names)
+	}
+}
+
+func TestPruneAge(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	writeLogs(t, dir,
+		"20261001-100000.000-old.json",
+		"20261002-100000.000-recent.json",
+		"20261003-100000.000-current.json",
+		"20261003-100000.000-old.txt",
+	)
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"20261001-100000.000-old.json", "20261003-100000.000-current.json", "20261003-100000.000-old.txt"} {
+		if err := os.Chtimes(filepath.Join(dir, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := prune(dir, filepath.Join(dir, "20261003-100000.000-current.json"), 0, 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"20261002-100000.000-recent.json",
+		"20261003-100000.000-current.json",
+		"20261003-100000.000-old.txt",
+	}
+	if names := remaining(t, dir); strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
@@ -0,0 +1,153 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+)
+
+// ListBuildsOptions filters ListBuilds
+type ListBuildsOptions struct {
+	Pipeline           string
+	Status             string
+	Branch             string
+	RuntimeEnvironment string
+	// Since and Until bound the creation time of the builds, zero for no bound
+	Since time.Time
+	Until time.Time
+	// Limit is the maximal number of builds, 0 for all the builds that match
+	Limit int
+	// MaxPages is the maximal number of pages of the build history that are read, 0 for no bound. The runtime
+	// environment and until filters run on the client, without a bound they can read the whole history
+	MaxPages int
+}
+
+// buildsPageSize is the number of builds fetched in a single API call
+const buildsPageSize = 100
+
+// ListBuilds lists the builds that match the filters, newest first. truncated is true when MaxPages stopped
+// the listing before the end of the history
+func (s *Service) ListBuilds(ctx context.Context, opt *ListBuildsOptions) (workflows Workflows, truncated bool, err error) {
+	query := &cfapi.ListWorkflowsOptions{
+		Status: opt.Status,
+		Branch: opt.Branch,
+		Limit:  buildsPageSize,
+	}
+	if opt.Pipeline != "" {
+		pipeline, err := s.api.GetPipeline(ctx, opt.Pipeline)
+		if err != nil {
+			return nil, false, err
+		}
+		query.PipelineID = pipeline.Metadata.ID
+	}
+
+	workflows = Workflows{}
+	for query.Page = 1; ; query.Page++ {
+		page, total, err := s.api.ListWorkflows(ctx, query)
+		if err != nil {
+			return nil, false, clierror.Errorf(clierror.KindOf(err), "Failed to get builds from Codefresh API: %v", err)
+		}
+		for _, wf := range page {
+			// the builds are sorted by creation time, the rest are older
+			if !opt.Since.IsZero() && wf.Created.Before(opt.Since) {
+				return workflows, false, nil
+			}
+			if matchWorkflow(wf, opt) {
+				workflows = append(workflows, wf)
+			}
+			if opt.Limit > 0 && len(workflows) >= opt.Limit {
+				return workflows, false, nil
+			}
+		}
+		if len(page) == 0 || (query.Page-1)*buildsPageSize+len(page) >= total {
+			return workflows, false, nil
+		}
+		if opt.MaxPages > 0 && query.Page >= opt.MaxPages {
+			return workflows, true, nil
+		}
+	}
+}
+
+// CancelBuild terminates a build that did not finish yet
+func (s *Service) CancelBuild(ctx context.Context, id string) error {
+	wf, err := s.api.GetWorkflow(ctx, id)
+	if err != nil {
+		return err
+	}
+	if cfapi.IsTerminal(wf.Status) {
+		return clierror.Errorf(clierror.Usage, "Build %s already finished with status %s", id, wf.Status)
+	}
+	return s.api.TerminateWorkflow(ctx, id)
+}
+
+// RestartBuild starts a new build with the parameters of a finished build,
+// fromFailed continues from the step that failed
+func (s *Service) RestartBuild(ctx context.Context, id string, fromFailed bool) (*Build, error) {
+	wf, err := s.api.GetWorkflow(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !cfapi.IsTerminal(wf.Status) {
+		return nil, clierror.Errorf(clierror.Usage, "Build %s is still %s, cancel it before restarting", id, wf.Status)
+	}
+	if fromFailed && wf.Status == cfapi.StatusSuccess {
+		return nil, clierror.Errorf(clierror.Usage, "Build %s succeeded, there is no failed step to restart from", id)
+	}
+	newID, err := s.api.RestartWorkflow(ctx, id, fromFailed)
+	if err != nil {
+		return nil, err
+	}
+	return &Build{
+		ID:       newID,
+		Pipeline: wf.PipelineName,
+		URL:      fmt.Sprintf("%s/build/%s", s.host, newID),
+	}, nil
+}
+
+// ApproveBuild approves, or denies when approve is false, a build that waits for approval
+func (s *Service) ApproveBuild(ctx context.Context, id string, approve bool) error {
+	wf, err := s.api.GetWorkflow(ctx, id)
+	if err != nil {
+		return err
+	}
+	if wf.Status != cfapi.StatusPendingApproval {
+		return clierror.Errorf(clierror.Usage, "Build %s is not waiting for approval, its status is %s", id, wf.Status)
+	}
+	return s.api.ApproveWorkflow(ctx, id, approve)
+}
+
+func matchWorkflow(wf *cfapi.Workflow, opt *ListBuildsOptions) bool {
+	if opt.Status != "" && wf.Status != opt.Status {
+		return false
+	}
+	if opt.Branch != "" && wf.BranchName != opt.Branch {
+		return false
+	}
+	if opt.RuntimeEnvironment != "" && wf.RuntimeEnvironment.Name != opt.RuntimeEnvironment {
+		return false
+	}
+	if !opt.Until.IsZero() && wf.Created.After(opt.Until) {
+		return false
+	}
+	return true
+}
+
+// ParseTime parses an absolute time (RFC3339 or a 2006-01-02 date) or a duration
+// before now such as 24h
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, clierror.Errorf(clierror.Usage, "Invalid time %q, use a duration (24h), a date (2006-01-02) or RFC3339", value)
+}
@@ -0,0 +1,90 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+)
+
+func (f *fakeAPI) ListWorkflows(ctx context.Context, opt *cfapi.ListWorkflowsOptions) ([]*cfapi.Workflow, int, error) {
+	f.pages++
+	start := (opt.Page - 1) * opt.Limit
+	end := start + opt.Limit
+	if end > len(f.history) {
+		end = len(f.history)
+	}
+	return f.history[start:end], len(f.history), nil
+}
+
+// buildHistory is n builds an hour apart on runtime environment kind/default, newest first, the last one ran on kind/other
+func buildHistory(n int, newest time.Time) []*cfapi.Workflow {
+	history := []*cfapi.Workflow{}
+	for i := 0; i < n; i++ {
+		wf := &cfapi.Workflow{ID: fmt.Sprintf("b%d", i), Created: newest.Add(-time.Duration(i) * time.Hour)}
+		wf.RuntimeEnvironment.Name = "kind/default"
+		history = append(history, wf)
+	}
+	history[n-1].RuntimeEnvironment.Name = "kind/other"
+	return history
+}
+
+func TestListBuilds(t *testing.T) {
+	newest := time.Date(2019, 10, 20, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		opt           ListBuildsOptions
+		wantBuilds    int
+		wantPages     int
+		wantTruncated bool
+	}{
+		{name: "limit", opt: ListBuildsOptions{Limit: 20}, wantBuilds: 20, wantPages: 1},
+		{name: "all", opt: ListBuildsOptions{}, wantBuilds: 450, wantPages: 5},
+		{name: "since stops the paging", opt: ListBuildsOptions{Since: newest.Add(-150 * time.Hour)}, wantBuilds: 151, wantPages: 2},
+		{name: "max pages", opt: ListBuildsOptions{RuntimeEnvironment: "kind/other", MaxPages: 2}, wantBuilds: 0, wantPages: 2, wantTruncated: true},
+		{name: "until", opt: ListBuildsOptions{Until: newest.Add(-400 * time.Hour), MaxPages: 10}, wantBuilds: 50, wantPages: 5},
+		{name: "rare match", opt: ListBuildsOptions{RuntimeEnvironment: "kind/other", MaxPages: 5}, wantBuilds: 1, wantPages: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeAPI{history: buildHistory(450, newest)}
+			s := newFakeService(api)
+			builds, truncated, err := s.ListBuilds(context.Background(), &tt.opt)
+			if err != nil {
+				t.Fatalf("ListBuilds() error = %v", err)
+			}
+			if len(builds) != tt.wantBuilds || api.pages != tt.wantPages || truncated != tt.wantTruncated {
+				t.Errorf("ListBuilds() = %d builds after %d pages, truncated %v, want %d builds after %d pages, truncated %v",
+					len(builds), api.pages, truncated, tt.wantBuilds, tt.wantPages, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2019, 10, 20, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "24h", want: now.Add(-24 * time.Hour)},
+		{value: "90m", want: now.Add(-90 * time.Minute)},
+		{value: "2019-10-01T08:30:00Z", want: time.Date(2019, 10, 1, 8, 30, 0, 0, time.UTC)},
+		{value: "2019-10-01", want: time.Date(2019, 10, 1, 0, 0, 0, 0, time.Local)},
+		{value: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTime(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+)
+
+// LogsOptions controls StreamLogs
+type LogsOptions struct {
+	// Step shows only the output of this step, empty for all the steps
+	Step string
+	// Follow keeps polling the build until it finishes
+	Follow   bool
+	Interval time.Duration
+}
+
+// logPrinter writes the output of the steps that was not written yet, with a header when a step
+// starts and a footer when it finishes
+type logPrinter struct {
+	w      io.Writer
+	prefix string
+	step   string
+
+	lines    map[string]int
+	started  map[string]bool
+	finished map[string]bool
+}
+
+func newLogPrinter(w io.Writer, prefix string, step string) *logPrinter {
+	return &logPrinter{
+		w:        w,
+		prefix:   prefix,
+		step:     step,
+		lines:    map[string]int{},
+		started:  map[string]bool{},
+		finished: map[string]bool{},
+	}
+}
+
+func (p *logPrinter) write(steps []*cfapi.Step) {
+	for _, step := range steps {
+		if p.step != "" && step.Name != p.step {
+			continue
+		}
+		if step.Status == cfapi.StatusPending || p.finished[step.Name] {
+			continue
+		}
+		if !p.started[step.Name] {
+			p.started[step.Name] = true
+			p.printf("%s\n", joinNonEmpty("==>", formatTimestamp(step.CreationTimeStamp), step.Name))
+		}
+		if p.lines[step.Name] > len(step.Logs) {
+			p.lines[step.Name] = 0
+		}
+		for _, log := range step.Logs[p.lines[step.Name]:] {
+			for _, line := range strings.Split(strings.TrimRight(log, "\n"), "\n") {
+				p.printf("%s\n", strings.TrimRight(line, "\r"))
+			}
+		}
+		p.lines[step.Name] = len(step.Logs)
+		if step.FinishTimeStamp != 0 {
+			p.finished[step.Name] = true
+			duration := formatDuration(step.Duration())
+			if duration != "" {
+				duration = "(" + duration + ")"
+			}
+			p.printf("%s\n", joinNonEmpty("<==", formatTimestamp(step.FinishTimeStamp), step.Name, step.Status, duration))
+		}
+	}
+}
+
+func (p *logPrinter) printf(format string, a ...interface{}) {
+	fmt.Fprint(p.w, p.prefix+fmt.Sprintf(format, a...))
+}
+
+// StreamLogs writes the output of the steps of the build to w, with Follow it waits for the build to finish
+// or ctx to be done
+func (s *Service) StreamLogs(ctx context.Context, id string, w io.Writer, opt *LogsOptions) error {
+	printer := newLogPrinter(w, "", opt.Step)
+	for {
+		wf, err := s.api.GetWorkflow(ctx, id)
+		if err != nil {
+			return err
+		}
+		if wf.Progress != "" {
+			progress, err := s.api.GetProgress(ctx, wf.Progress)
+			if err != nil {
+				return err
+			}
+			if opt.Step != "" && !hasStep(progress.Steps, opt.Step) && cfapi.IsTerminal(wf.Status) {
+				return clierror.Errorf(clierror.Usage, "Build %s has no step %s", id, opt.Step)
+			}
+			printer.write(progress.Steps)
+		}
+		if !opt.Follow || cfapi.IsTerminal(wf.Status) {
+			return nil
+		}
+		if err := sleep(ctx, opt.Interval); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep waits for d, it returns the error of ctx when ctx is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return clierror.FromContext(ctx, "Waiting for the build")
+	}
+}
+
+func hasStep(steps []*cfapi.Step, name string) bool {
+	for _, step := range steps {
+		if step.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func joinNonEmpty(fields ...string) string {
+	nonEmpty := []string{}
+	for _, f := range fields {
+		if f != "" {
+			nonEmpty = append(nonEmpty, f)
+		}
+	}
+	return strings.Join(nonEmpty, " ")
+}
+
+func formatTimestamp(unix int64) string {
+	if unix == 0 {
+		return ""
+	}
+	return formatTime(time.Unix(unix, 0))
+}
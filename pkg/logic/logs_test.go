@@ -0,0 +1,51 @@
+package logic
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+)
+
+func TestLogPrinter(t *testing.T) {
+	start := time.Date(2019, 10, 20, 12, 0, 0, 0, time.Local).Unix()
+	startedAt := formatTimestamp(start)
+	finishedAt := formatTimestamp(start + 5)
+	buf := &bytes.Buffer{}
+	p := newLogPrinter(buf, "[p] ", "")
+
+	p.write([]*cfapi.Step{
+		{Name: "clone", Status: cfapi.StatusRunning, CreationTimeStamp: start, Logs: []string{"cloning\n"}},
+		{Name: "build", Status: cfapi.StatusPending},
+	})
+	p.write([]*cfapi.Step{
+		{Name: "clone", Status: cfapi.StatusSuccess, CreationTimeStamp: start, FinishTimeStamp: start + 5, Logs: []string{"cloning\n", "done\r\nok"}},
+		{Name: "build", Status: cfapi.StatusPending},
+	})
+	// finished steps are not written again
+	p.write([]*cfapi.Step{
+		{Name: "clone", Status: cfapi.StatusSuccess, CreationTimeStamp: start, FinishTimeStamp: start + 5, Logs: []string{"cloning\n", "done\r\nok"}},
+	})
+
+	want := "[p] ==> " + startedAt + " clone\n" +
+		"[p] cloning\n" +
+		"[p] done\n" +
+		"[p] ok\n" +
+		"[p] <== " + finishedAt + " clone success (5s)\n"
+	if got := buf.String(); got != want {
+		t.Errorf("write() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLogPrinterStep(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := newLogPrinter(buf, "", "build")
+	p.write([]*cfapi.Step{
+		{Name: "clone", Status: cfapi.StatusRunning, Logs: []string{"cloning"}},
+		{Name: "build", Status: cfapi.StatusRunning, Logs: []string{"building"}},
+	})
+	if want := "==> build\nbuilding\n"; buf.String() != want {
+		t.Errorf("write() = %q, want %q", buf.String(), want)
+	}
+}
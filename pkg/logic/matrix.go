@@ -0,0 +1,98 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+)
+
+// RunPipelines runs the pipelines with at most parallelism builds at a time and waits for each one
+// unless wait is nil. The builds are returned in the order of the names, with one error per pipeline
+// (nil when its build succeeded). Once ctx is done no build is started and the waiting stops
+func (s *Service) RunPipelines(ctx context.Context, names []string, run *RunOptions, wait *WaitOptions, parallelism int) (Builds, []error) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	builds := make(Builds, len(names))
+	errs := make([]error, len(names))
+	sem := make(chan struct{}, parallelism)
+	wg := sync.WaitGroup{}
+	for i, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, name string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := clierror.FromContext(ctx, "Running the pipeline"); err != nil {
+				builds[i] = &Build{Pipeline: name, Reason: "not started"}
+				errs[i] = err
+				return
+			}
+			build, err := s.ExecutePipeline(ctx, name, run)
+			if err != nil {
+				builds[i] = &Build{Pipeline: name, Reason: fmt.Sprintf("failed to start: %v", err)}
+				errs[i] = err
+				return
+			}
+			builds[i] = build
+			if wait != nil {
+				if wait.OnStart != nil {
+					wait.OnStart(build)
+				}
+				errs[i] = s.WaitForBuild(ctx, build, wait)
+			}
+		}(i, name)
+	}
+	wg.Wait()
+	return builds, errs
+}
+
+// FirstError summarizes the errors of RunPipelines, the kind of the first failure is kept
+func FirstError(errs []error) error {
+	var first error
+	failed := 0
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		failed++
+		if first == nil {
+			first = err
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	if len(errs) == 1 {
+		return first
+	}
+	return clierror.Errorf(clierror.KindOf(first), "%d of %d pipelines failed, first failure: %v", failed, len(errs), first)
+}
+
+// ReadPipelinesFile reads pipeline names from a file, one per line, # starts a comment
+func ReadPipelinesFile(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, clierror.Errorf(clierror.Config, "Failed to read pipelines file: %v", err)
+	}
+	names := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			names = append(names, line)
+		}
+	}
+	return names, scanner.Err()
+}
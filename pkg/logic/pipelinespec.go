@@ -0,0 +1,201 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"github.com/sharon-vendrov/sharoncli/pkg/diff"
+	"sigs.k8s.io/yaml"
+)
+
+// Actions of ApplyPipelines
+const (
+	ApplyCreated    = "created"
+	ApplyConfigured = "configured"
+	ApplyUnchanged  = "unchanged"
+)
+
+// managedMetadata are the metadata fields set by Codefresh, they are not exported
+var managedMetadata = []string{"id", "accountId", "created_at", "updated_at", "deprecate"}
+
+type (
+	// ApplyResult is what ApplyPipelines did with one pipeline
+	ApplyResult struct {
+		Name   string `json:"name"`
+		Action string `json:"action"`
+		DryRun bool   `json:"dryRun,omitempty"`
+	}
+
+	// ApplyResults is a printable list of apply results
+	ApplyResults []*ApplyResult
+
+	// ApplyOptions of ApplyPipelines
+	ApplyOptions struct {
+		DryRun bool
+		// OnDiff is called with the unified diff of every pipeline that changes
+		OnDiff func(name string, diff string)
+	}
+)
+
+// ExportPipeline returns the pipeline without the fields managed by Codefresh,
+// so it can be applied back with ApplyPipelines
+func (s *Service) ExportPipeline(ctx context.Context, name string) (map[string]interface{}, error) {
+	p, err := s.api.GetPipeline(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return exportable(p.Raw()), nil
+}
+
+// ReadPipelineSpecs reads the pipelines of a yaml or json file, - reads stdin.
+// A yaml file may hold several pipelines separated by ---
+func ReadPipelineSpecs(path string) ([]map[string]interface{}, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, clierror.Errorf(clierror.Config, "Failed to read pipelines: %v", err)
+	}
+	specs := []map[string]interface{}{}
+	for i, doc := range splitDocuments(data) {
+		spec := map[string]interface{}{}
+		if err := yaml.Unmarshal(doc, &spec); err != nil {
+			return nil, clierror.Errorf(clierror.Config, "Failed to parse document %d of %s: %v", i+1, path, err)
+		}
+		if len(spec) == 0 {
+			continue
+		}
+		if pipelineName(spec) == "" {
+			return nil, clierror.Errorf(clierror.Config, "Document %d of %s has no metadata.name", i+1, path)
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// ApplyPipelines creates the pipelines that do not exist and updates the ones that changed
+func (s *Service) ApplyPipelines(ctx context.Context, specs []map[string]interface{}, opt *ApplyOptions) (ApplyResults, error) {
+	results := ApplyResults{}
+	for _, spec := range specs {
+		name := pipelineName(spec)
+		desired := exportable(spec)
+		desiredYAML, err := yaml.Marshal(desired)
+		if err != nil {
+			return results, err
+		}
+
+		liveYAML := []byte{}
+		exists := true
+		live, err := s.api.GetPipeline(ctx, name)
+		if cfapi.IsNotFound(err) {
+			exists = false
+		} else if err != nil {
+			return results, err
+		} else if liveYAML, err = yaml.Marshal(exportable(live.Raw())); err != nil {
+			return results, err
+		}
+
+		result := &ApplyResult{Name: name, Action: ApplyUnchanged, DryRun: opt.DryRun}
+		results = append(results, result)
+		d := diff.Unified(string(liveYAML), string(desiredYAML), name+" (live)", name+" (file)")
+		if d == "" {
+			continue
+		}
+		if opt.OnDiff != nil {
+			opt.OnDiff(name, d)
+		}
+		if exists {
+			result.Action = ApplyConfigured
+		} else {
+			result.Action = ApplyCreated
+		}
+		if opt.DryRun {
+			continue
+		}
+		if exists {
+			_, err = s.api.ReplacePipeline(ctx, name, desired)
+		} else {
+			_, err = s.api.CreatePipeline(ctx, desired)
+		}
+		if err != nil {
+			return results, clierror.Errorf(clierror.KindOf(err), "Failed to apply pipeline %s: %v", name, err)
+		}
+	}
+	return results, nil
+}
+
+// exportable copies the pipeline without the fields managed by Codefresh
+func exportable(pipeline map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range pipeline {
+		out[k] = v
+	}
+	if metadata, ok := pipeline["metadata"].(map[string]interface{}); ok {
+		m := map[string]interface{}{}
+		for k, v := range metadata {
+			m[k] = v
+		}
+		for _, field := range managedMetadata {
+			delete(m, field)
+		}
+		out["metadata"] = m
+	}
+	if _, ok := out["version"]; !ok {
+		out["version"] = "1.0"
+	}
+	if _, ok := out["kind"]; !ok {
+		out["kind"] = "pipeline"
+	}
+	return out
+}
+
+func pipelineName(spec map[string]interface{}) string {
+	metadata, _ := spec["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	return name
+}
+
+func splitDocuments(data []byte) [][]byte {
+	docs := [][]byte{}
+	current := &bytes.Buffer{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		if strings.TrimRight(scanner.Text(), " ") == "---" {
+			docs = append(docs, current.Bytes())
+			current = &bytes.Buffer{}
+			continue
+		}
+		fmt.Fprintln(current, scanner.Text())
+	}
+	return append(docs, current.Bytes())
+}
+
+// Header implements printer.Table
+func (r ApplyResults) Header(wide bool) []string {
+	return []string{"PIPELINE", "ACTION"}
+}
+
+// Rows implements printer.Table
+func (r ApplyResults) Rows(wide bool) [][]string {
+	rows := [][]string{}
+	for _, result := range r {
+		action := result.Action
+		if result.DryRun {
+			action += " (dry run)"
+		}
+		rows = append(rows, []string{result.Name, action})
+	}
+	return rows
+}
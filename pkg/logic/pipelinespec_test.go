@@ -0,0 +1,95 @@
+package logic
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+)
+
+func TestReadPipelineSpecs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pipelines")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		names   []string
+		err     string
+	}{
+		{
+			name: "several documents",
+			file: "pipelines.yaml",
+			content: `---
+metadata:
+  name: default/build
+spec:
+  steps:
+    main:
+      image: alpine
+---
+
+---
+# only a comment
+---
+metadata:
+  name: default/deploy
+`,
+			names: []string{"default/build", "default/deploy"},
+		},
+		{
+			name:    "json",
+			file:    "pipeline.json",
+			content: `{"metadata": {"name": "default/build"}, "spec": {}}`,
+			names:   []string{"default/build"},
+		},
+		{
+			name:    "document without a name",
+			file:    "noname.yaml",
+			content: "metadata:\n  name: default/build\n---\nspec: {}\n",
+			err:     "Document 2 of %s has no metadata.name",
+		},
+		{
+			name:    "invalid document",
+			file:    "invalid.yaml",
+			content: "metadata: [\n",
+			err:     "Failed to parse document 1 of %s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			specs, err := ReadPipelineSpecs(path)
+			if tt.err != "" {
+				if clierror.KindOf(err) != clierror.Config || !strings.HasPrefix(err.Error(), strings.Replace(tt.err, "%s", path, 1)) {
+					t.Errorf("ReadPipelineSpecs() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadPipelineSpecs() error = %v", err)
+			}
+			names := []string{}
+			for _, spec := range specs {
+				names = append(names, pipelineName(spec))
+			}
+			if strings.Join(names, ",") != strings.Join(tt.names, ",") {
+				t.Errorf("ReadPipelineSpecs() = %v, want %v", names, tt.names)
+			}
+		})
+	}
+
+	if _, err := ReadPipelineSpecs(filepath.Join(dir, "missing.yaml")); clierror.KindOf(err) != clierror.Config {
+		t.Errorf("ReadPipelineSpecs() of a missing file error = %v, want a config error", err)
+	}
+}
@@ -0,0 +1,60 @@
+package logic
+
+import (
+	"context"
+
+	"github.com/codefresh-io/go-sdk/pkg/codefresh"
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+)
+
+type (
+	// API is the part of the Codefresh API the SDK does not cover, implemented by *cfapi.Client
+	API interface {
+		GetPipeline(ctx context.Context, name string) (*cfapi.Pipeline, error)
+		ListPipelines(ctx context.Context, limit int, offset int) ([]*cfapi.Pipeline, int, error)
+		CreatePipeline(ctx context.Context, pipeline map[string]interface{}) (*cfapi.Pipeline, error)
+		ReplacePipeline(ctx context.Context, name string, pipeline map[string]interface{}) (*cfapi.Pipeline, error)
+		DeletePipeline(ctx context.Context, name string) error
+		RunPipeline(ctx context.Context, name string, opt *cfapi.RunOptions) (string, error)
+		GetBranch(ctx context.Context, gitContext string, repo string, branch string) (*cfapi.Branch, error)
+		GetWorkflow(ctx context.Context, id string) (*cfapi.Workflow, error)
+		ListWorkflows(ctx context.Context, opt *cfapi.ListWorkflowsOptions) ([]*cfapi.Workflow, int, error)
+		TerminateWorkflow(ctx context.Context, id string) error
+		RestartWorkflow(ctx context.Context, id string, fromFailed bool) (string, error)
+		ApproveWorkflow(ctx context.Context, id string, approve bool) error
+		GetProgress(ctx context.Context, id string) (*cfapi.Progress, error)
+	}
+
+	// Options of the service
+	Options struct {
+		// Codefresh is the SDK client, used for the runtime environments
+		Codefresh codefresh.Codefresh
+		// API is the client of the pipelines, builds and logs, a cfapi client of Host and Token when it is nil
+		API API
+		// Host is the base of the build URLs
+		Host string
+		// Token is only used by the default API client
+		Token string
+	}
+
+	// Service runs pipelines and manages pipelines and builds in Codefresh
+	Service struct {
+		codefresh codefresh.Codefresh
+		api       API
+		host      string
+	}
+)
+
+// New creates a service, the commands create it from the clients of the Codefresh context
+// and the tests from fakes
+func New(opt *Options) *Service {
+	api := opt.API
+	if api == nil {
+		api = cfapi.New(&cfapi.Options{Host: opt.Host, Token: opt.Token})
+	}
+	return &Service{
+		codefresh: opt.Codefresh,
+		api:       api,
+		host:      opt.Host,
+	}
+}
@@ -0,0 +1,83 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"sigs.k8s.io/yaml"
+)
+
+// smokeTestSpec exercises a freestyle step, a docker build and the pipeline volume,
+// every step has its own stage so they run in order
+const smokeTestSpec = `
+version: "1.0"
+stages:
+  - freestyle
+  - volume-write
+  - docker-build
+  - volume-read
+steps:
+  freestyle:
+    title: Run a freestyle step
+    stage: freestyle
+    image: alpine:3.10
+    commands:
+      - echo "sharoncli smoke test ${{CF_BUILD_ID}}"
+  volume_write:
+    title: Write to the pipeline volume
+    stage: volume-write
+    image: alpine:3.10
+    commands:
+      - mkdir -p /codefresh/volume/sharoncli-smoke
+      - echo "${{CF_BUILD_ID}}" > /codefresh/volume/sharoncli-smoke/build-id
+      - printf 'FROM alpine:3.10\nRUN echo sharoncli smoke test\n' > /codefresh/volume/sharoncli-smoke/Dockerfile
+  docker_build:
+    title: Build a docker image
+    stage: docker-build
+    type: build
+    image_name: sharoncli-smoke
+    tag: ${{CF_BUILD_ID}}
+    working_directory: /codefresh/volume/sharoncli-smoke
+    dockerfile: Dockerfile
+    disable_push: true
+  volume_read:
+    title: Read from the pipeline volume
+    stage: volume-read
+    image: alpine:3.10
+    commands:
+      - test "$(cat /codefresh/volume/sharoncli-smoke/build-id)" = "${{CF_BUILD_ID}}"
+`
+
+// CreateSmokeTestPipeline creates an ephemeral pipeline from the built-in smoke test spec,
+// bound to the runtime environment when it is set. The returned function deletes the pipeline, also once ctx is done
+func (s *Service) CreateSmokeTestPipeline(ctx context.Context, runtimeEnvironment string) (string, func() error, error) {
+	spec := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(smokeTestSpec), &spec); err != nil {
+		return "", nil, err
+	}
+	delete(spec, "version")
+	if runtimeEnvironment != "" {
+		spec["runtimeEnvironment"] = map[string]interface{}{
+			"name": runtimeEnvironment,
+		}
+	}
+	name := fmt.Sprintf("sharoncli-smoke-%s", time.Now().Format("20060102-150405"))
+	_, err := s.api.CreatePipeline(ctx, map[string]interface{}{
+		"version": "1.0",
+		"kind":    "pipeline",
+		"metadata": map[string]interface{}{
+			"name":               name,
+			"originalYamlString": smokeTestSpec,
+		},
+		"spec": spec,
+	})
+	if err != nil {
+		return "", nil, clierror.Errorf(clierror.KindOf(err), "Failed to create the smoke test pipeline: %v", err)
+	}
+
+	return name, func() error {
+		return s.api.DeletePipeline(context.Background(), name)
+	}, nil
+}
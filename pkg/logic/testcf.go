@@ -1,54 +1,261 @@
 package logic
 
 import (
+	"context"
 	"fmt"
-	"os"
+	"io"
+	"regexp"
+	"time"
 
-	"github.com/codefresh-io/go-sdk/pkg/codefresh"
-	"github.com/codefresh-io/go-sdk/pkg/utils"
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
 )
 
+// RunOptions of the pipeline run
+type RunOptions struct {
+	Branch      string
+	SHA         string
+	Trigger     string
+	Variables   map[string]string
+	NoCache     bool
+	ResetVolume bool
+	// RuntimeEnvironment overrides the runtime environment of the pipeline for this run
+	RuntimeEnvironment string
+}
+
 // ExecutePipeline execute CF pipeline
-func ExecutePipeline(pipelineName string) error {
-	path := fmt.Sprintf("%s/.cfconfig", os.Getenv("HOME"))
-	options, err := utils.ReadAuthContext(path, "")
+func (s *Service) ExecutePipeline(ctx context.Context, pipelineName string, opt *RunOptions) (*Build, error) {
+	if err := s.validateRunOptions(ctx, pipelineName, opt); err != nil {
+		return nil, err
+	}
+	id, err := s.api.RunPipeline(ctx, pipelineName, &cfapi.RunOptions{
+		Branch:             opt.Branch,
+		SHA:                opt.SHA,
+		Trigger:            opt.Trigger,
+		Variables:          opt.Variables,
+		NoCache:            opt.NoCache,
+		ResetVolume:        opt.ResetVolume,
+		RuntimeEnvironment: opt.RuntimeEnvironment,
+	})
 	if err != nil {
-		fmt.Println("Failed to read codefresh config file")
-		return (err)
-	}
-	clientOptions := codefresh.ClientOptions{Host: options.URL,
-		Auth: codefresh.AuthOptions{Token: options.Token}}
-	cf := codefresh.New(&clientOptions)
-	runOptions := codefresh.RunOptions{Branch: "string"}
-	resp, err := cf.Pipelines().Run(pipelineName, &runOptions)
+		return nil, err
+	}
+
+	return &Build{
+		ID:       id,
+		Pipeline: pipelineName,
+		URL:      fmt.Sprintf("%s/build/%s", s.host, id),
+	}, nil
+}
+
+// CheckRuntimeEnvironment makes sure the runtime environment exists and is online, the commands check it once
+// before they run pipelines on it
+func (s *Service) CheckRuntimeEnvironment(name string) error {
+	re, err := s.codefresh.RuntimeEnvironments().Get(name)
 	if err != nil {
-		fmt.Println("Failed to get run pipeline")
-		return (err)
+		return clierror.Errorf(clierror.API, "Runtime environment %s is not available: %v", name, err)
+	}
+	if re.Metadata.Name == "" {
+		return clierror.Errorf(clierror.Usage, "Runtime environment %s was not found", name)
+	}
+	if !cfapi.IsOnline(re) {
+		return clierror.Errorf(clierror.API, "Runtime environment %s is offline", name)
 	}
+	return nil
+}
 
-	fmt.Println(resp)
+// validateRunOptions makes sure the trigger exists and that the branch exists in its repository
+func (s *Service) validateRunOptions(ctx context.Context, pipelineName string, opt *RunOptions) error {
+	if opt.Branch == "" && opt.Trigger == "" {
+		return nil
+	}
+	pipeline, err := s.api.GetPipeline(ctx, pipelineName)
+	if err != nil {
+		return err
+	}
+	trigger := pipeline.GitTrigger(opt.Trigger)
+	if trigger == nil {
+		if opt.Trigger != "" {
+			return clierror.Errorf(clierror.Usage, "Pipeline %s has no git trigger named %s", pipelineName, opt.Trigger)
+		}
+		// not git triggered, nothing to validate the branch against
+		return nil
+	}
+	if opt.Branch == "" {
+		return nil
+	}
+	if _, err := s.api.GetBranch(ctx, trigger.Context, trigger.Repo, opt.Branch); err != nil {
+		if clierror.KindOf(err) == clierror.Auth {
+			return err
+		}
+		return clierror.Errorf(clierror.Usage, "Branch %s was not found in %s: %v", opt.Branch, trigger.Repo, err)
+	}
 	return nil
 }
 
+// ListPipelinesOptions filters and paginates ListPipelines
+type ListPipelinesOptions struct {
+	Project string
+	// Labels the pipelines must have, all of them
+	Labels []string
+	// NameRegex is matched against the full name (project/pipeline)
+	NameRegex string
+	// Limit is the size of a page, 0 for all the pipelines
+	Limit int
+	// Page starts from 1
+	Page int
+}
+
+// pipelinesPageSize is the number of pipelines fetched in a single API call
+const pipelinesPageSize = 100
+
 // ListPipelines lists all pipelines
-func ListPipelines() error {
-	path := fmt.Sprintf("%s/.cfconfig", os.Getenv("HOME"))
-	options, err := utils.ReadAuthContext(path, "")
-	if err != nil {
-		fmt.Println("Failed to read codefresh config file")
-		return (err)
+func (s *Service) ListPipelines(ctx context.Context, opt *ListPipelinesOptions) (Pipelines, error) {
+	var nameRegex *regexp.Regexp
+	if opt.NameRegex != "" {
+		var err error
+		if nameRegex, err = regexp.Compile(opt.NameRegex); err != nil {
+			return nil, clierror.Errorf(clierror.Usage, "Invalid name regex: %v", err)
+		}
 	}
-	clientOptions := codefresh.ClientOptions{Host: options.URL,
-		Auth: codefresh.AuthOptions{Token: options.Token}}
-	cf := codefresh.New(&clientOptions)
-	pipelines, err := cf.Pipelines().List()
+
+	pipelines := Pipelines{}
+	for offset := 0; ; offset += pipelinesPageSize {
+		page, count, err := s.api.ListPipelines(ctx, pipelinesPageSize, offset)
+		if err != nil {
+			return nil, clierror.Errorf(clierror.KindOf(err), "Failed to get Pipelines from Codefresh API: %v", err)
+		}
+		for _, p := range page {
+			if matchPipeline(p, opt, nameRegex) {
+				pipelines = append(pipelines, p)
+			}
+		}
+		if len(page) == 0 || offset+len(page) >= count {
+			break
+		}
+	}
+
+	if opt.Limit > 0 {
+		page := opt.Page
+		if page < 1 {
+			page = 1
+		}
+		start := (page - 1) * opt.Limit
+		if start > len(pipelines) {
+			start = len(pipelines)
+		}
+		end := start + opt.Limit
+		if end > len(pipelines) {
+			end = len(pipelines)
+		}
+		pipelines = pipelines[start:end]
+	}
+	return pipelines, nil
+}
+
+func matchPipeline(p *cfapi.Pipeline, opt *ListPipelinesOptions, nameRegex *regexp.Regexp) bool {
+	if opt.Project != "" && p.Metadata.Project != opt.Project {
+		return false
+	}
+	if nameRegex != nil && !nameRegex.MatchString(p.Metadata.Name) {
+		return false
+	}
+	tags := map[string]bool{}
+	for _, tag := range p.Metadata.Labels.Tags {
+		tags[tag] = true
+	}
+	for _, label := range opt.Labels {
+		if !tags[label] {
+			return false
+		}
+	}
+	return true
+}
+
+// GetPipeline returns the pipeline with the name
+func (s *Service) GetPipeline(ctx context.Context, name string) (*PipelineDetails, error) {
+	p, err := s.api.GetPipeline(ctx, name)
 	if err != nil {
-		fmt.Println("Failed to get Pipelines from Codefresh API")
-		return (err)
+		return nil, err
+	}
+	return &PipelineDetails{p}, nil
+}
+
+// WaitOptions controls how WaitForBuild polls the build
+type WaitOptions struct {
+	Interval time.Duration
+	// OnStart is called once the build was started, before waiting for it
+	OnStart func(build *Build)
+	// OnStep is called every time a step of the build changes its status
+	OnStep func(build *Build, step *cfapi.Step)
+	// Logs receives the output of the steps while the build runs, the lines are prefixed with the pipeline name
+	Logs io.Writer
+}
+
+// WaitForBuild polls the build until it reaches a terminal status and updates its status and duration,
+// builds that did not succeed are clierror.PipelineFailed errors. It stops waiting when ctx is done,
+// the build keeps running
+func (s *Service) WaitForBuild(ctx context.Context, build *Build, opt *WaitOptions) error {
+	steps := map[string]string{}
+	var failedStep string
+	var logs *logPrinter
+	if opt.Logs != nil {
+		logs = newLogPrinter(opt.Logs, fmt.Sprintf("[%s] ", build.Pipeline), "")
 	}
-	for _, p := range pipelines {
-		fmt.Printf("Pipeline: %+v\n\n", p)
+	for {
+		wf, err := s.api.GetWorkflow(ctx, build.ID)
+		if err != nil {
+			return err
+		}
+		build.Status = wf.Status
+		build.Duration = wf.Duration()
+
+		if wf.Progress != "" {
+			progress, err := s.api.GetProgress(ctx, wf.Progress)
+			if err != nil {
+				return err
+			}
+			if logs != nil {
+				logs.write(progress.Steps)
+			}
+			build.Steps = []*BuildStep{}
+			for _, step := range progress.Steps {
+				build.Steps = append(build.Steps, &BuildStep{
+					Name:     step.Name,
+					Status:   step.Status,
+					Duration: step.Duration(),
+				})
+				if step.Status == cfapi.StatusError {
+					failedStep = step.Name
+				}
+				if steps[step.Name] == step.Status {
+					continue
+				}
+				steps[step.Name] = step.Status
+				if opt.OnStep != nil {
+					opt.OnStep(build, step)
+				}
+			}
+		}
+
+		if cfapi.IsTerminal(wf.Status) {
+			break
+		}
+		if err := sleep(ctx, opt.Interval); err != nil {
+			build.Reason = "interrupted"
+			if clierror.KindOf(err) == clierror.Timeout {
+				build.Reason = "did not finish in time"
+			}
+			return clierror.Errorf(clierror.KindOf(err), "Build %s %s, last status is %s", build.ID, build.Reason, wf.Status)
+		}
 	}
 
+	if build.Status != cfapi.StatusSuccess {
+		build.Reason = fmt.Sprintf("finished with status %s", build.Status)
+		if failedStep != "" {
+			build.Reason = fmt.Sprintf("step %s failed", failedStep)
+		}
+		return clierror.Errorf(clierror.PipelineFailed, "Build %s %s", build.ID, build.Reason)
+	}
 	return nil
 }
@@ -1,19 +1,222 @@
 package logic
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codefresh-io/go-sdk/pkg/codefresh"
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+)
+
+// fakeCodefresh embeds the SDK interface, calls that are not faked panic
+type fakeCodefresh struct {
+	codefresh.Codefresh
+	runtimeEnvironments map[string]*codefresh.RuntimeEnvironment
+}
+
+func (f *fakeCodefresh) RuntimeEnvironments() codefresh.IRuntimeEnvironmentAPI {
+	return &fakeRuntimeEnvironments{environments: f.runtimeEnvironments}
+}
+
+type fakeRuntimeEnvironments struct {
+	codefresh.IRuntimeEnvironmentAPI
+	environments map[string]*codefresh.RuntimeEnvironment
+}
+
+func (f *fakeRuntimeEnvironments) Get(name string) (*codefresh.RuntimeEnvironment, error) {
+	if re, ok := f.environments[name]; ok {
+		return re, nil
+	}
+	// the SDK decodes the error response into an empty runtime environment
+	return &codefresh.RuntimeEnvironment{}, nil
+}
+
+// fakeAPI embeds the API interface, calls that are not faked panic
+type fakeAPI struct {
+	API
+	pipelines []*cfapi.Pipeline
+	// workflows are returned one after the other by GetWorkflow, the last one repeats
+	workflows []*cfapi.Workflow
+	progress  *cfapi.Progress
+	runs      []*cfapi.RunOptions
+	// history is the build history of ListWorkflows, newest first, and pages counts its calls
+	history []*cfapi.Workflow
+	pages   int
+}
+
+func (f *fakeAPI) GetPipeline(ctx context.Context, name string) (*cfapi.Pipeline, error) {
+	for _, p := range f.pipelines {
+		if p.Metadata.Name == name {
+			return p, nil
+		}
+	}
+	return nil, clierror.New(clierror.API, &cfapi.ResponseError{StatusCode: 404, Message: "not found"})
+}
+
+func (f *fakeAPI) ListPipelines(ctx context.Context, limit int, offset int) ([]*cfapi.Pipeline, int, error) {
+	end := offset + limit
+	if end > len(f.pipelines) {
+		end = len(f.pipelines)
+	}
+	return f.pipelines[offset:end], len(f.pipelines), nil
+}
+
+func (f *fakeAPI) RunPipeline(ctx context.Context, name string, opt *cfapi.RunOptions) (string, error) {
+	f.runs = append(f.runs, opt)
+	return fmt.Sprintf("build-%d", len(f.runs)), nil
+}
+
+func (f *fakeAPI) GetWorkflow(ctx context.Context, id string) (*cfapi.Workflow, error) {
+	wf := f.workflows[0]
+	if len(f.workflows) > 1 {
+		f.workflows = f.workflows[1:]
+	}
+	return wf, nil
+}
+
+func (f *fakeAPI) GetProgress(ctx context.Context, id string) (*cfapi.Progress, error) {
+	return f.progress, nil
+}
+
+func newFakeService(api *fakeAPI, environments ...*codefresh.RuntimeEnvironment) *Service {
+	cf := &fakeCodefresh{runtimeEnvironments: map[string]*codefresh.RuntimeEnvironment{}}
+	for _, re := range environments {
+		cf.runtimeEnvironments[re.Metadata.Name] = re
+	}
+	return New(&Options{Codefresh: cf, API: api, Host: "https://g.codefresh.io"})
+}
+
+func runtimeEnvironment(name string, status string) *codefresh.RuntimeEnvironment {
+	re := &codefresh.RuntimeEnvironment{}
+	re.Metadata.Name = name
+	re.Status.Message = status
+	return re
+}
 
 func TestExecutePipeline(t *testing.T) {
-	err := ExecutePipeline("default/MyPipeline")
+	api := &fakeAPI{}
+	s := newFakeService(api, runtimeEnvironment("kind/default", "online"))
+	build, err := s.ExecutePipeline(context.Background(), "default/MyPipeline", &RunOptions{
+		Variables:          map[string]string{"KEY": "value"},
+		RuntimeEnvironment: "kind/default",
+	})
 	if err != nil {
-		t.Fail()
+		t.Fatalf("ExecutePipeline() error = %v", err)
+	}
+	if build.ID != "build-1" || build.URL != "https://g.codefresh.io/build/build-1" {
+		t.Errorf("ExecutePipeline() = %+v", build)
 	}
+	if len(api.runs) != 1 || api.runs[0].RuntimeEnvironment != "kind/default" || api.runs[0].Variables["KEY"] != "value" {
+		t.Errorf("RunPipeline() was called with %+v", api.runs)
+	}
+}
 
+func TestCheckRuntimeEnvironment(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		wantKind clierror.Kind
+	}{
+		{name: "online", env: "kind/default", wantKind: clierror.Unknown},
+		{name: "offline", env: "kind/offline", wantKind: clierror.API},
+		{name: "missing", env: "kind/missing", wantKind: clierror.Usage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeService(&fakeAPI{}, runtimeEnvironment("kind/default", "online"), runtimeEnvironment("kind/offline", "Offline"))
+			if err := s.CheckRuntimeEnvironment(tt.env); clierror.KindOf(err) != tt.wantKind || tt.wantKind == clierror.Unknown && err != nil {
+				t.Errorf("CheckRuntimeEnvironment() error = %v, want kind %v", err, tt.wantKind)
+			}
+		})
+	}
 }
 
 func TestListPipelines(t *testing.T) {
-	err := ListPipelines()
+	api := &fakeAPI{}
+	for i := 0; i < 250; i++ {
+		p := &cfapi.Pipeline{}
+		p.Metadata.Name = fmt.Sprintf("default/p%d", i)
+		p.Metadata.Project = "default"
+		if i%2 == 0 {
+			p.Metadata.Project = "other"
+		}
+		api.pipelines = append(api.pipelines, p)
+	}
+	s := newFakeService(api)
+
+	all, err := s.ListPipelines(context.Background(), &ListPipelinesOptions{})
 	if err != nil {
-		t.Fail()
+		t.Fatalf("ListPipelines() error = %v", err)
+	}
+	if len(all) != 250 {
+		t.Errorf("ListPipelines() returned %d pipelines, want 250", len(all))
 	}
 
+	page, err := s.ListPipelines(context.Background(), &ListPipelinesOptions{Project: "default", NameRegex: "p1", Limit: 10, Page: 2})
+	if err != nil {
+		t.Fatalf("ListPipelines() error = %v", err)
+	}
+	names := []string{}
+	for _, p := range page {
+		names = append(names, p.Metadata.Name)
+	}
+	want := "default/p109,default/p111,default/p113,default/p115,default/p117,default/p119,default/p121,default/p123,default/p125,default/p127"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("ListPipelines() = %s, want %s", got, want)
+	}
+}
+
+func TestWaitForBuild(t *testing.T) {
+	api := &fakeAPI{
+		workflows: []*cfapi.Workflow{
+			{ID: "b1", Status: cfapi.StatusRunning, Progress: "p1"},
+			{ID: "b1", Status: cfapi.StatusError, Progress: "p1"},
+		},
+		progress: &cfapi.Progress{Steps: []*cfapi.Step{
+			{Name: "clone", Status: cfapi.StatusSuccess},
+			{Name: "build", Status: cfapi.StatusError},
+		}},
+	}
+	s := newFakeService(api)
+	build := &Build{ID: "b1", Pipeline: "default/MyPipeline"}
+	steps := 0
+	err := s.WaitForBuild(context.Background(), build, &WaitOptions{
+		OnStep: func(*Build, *cfapi.Step) { steps++ },
+	})
+	if clierror.KindOf(err) != clierror.PipelineFailed {
+		t.Errorf("WaitForBuild() error = %v, want a failed pipeline", err)
+	}
+	if build.Status != cfapi.StatusError || build.Reason != "step build failed" || len(build.Steps) != 2 {
+		t.Errorf("WaitForBuild() build = %+v", build)
+	}
+	if steps != 2 {
+		t.Errorf("OnStep was called %d times, want 2", steps)
+	}
+}
+
+func TestWaitForBuildTimeout(t *testing.T) {
+	api := &fakeAPI{workflows: []*cfapi.Workflow{{ID: "b1", Status: cfapi.StatusRunning}}}
+	s := newFakeService(api)
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	build := &Build{ID: "b1"}
+	err := s.WaitForBuild(ctx, build, &WaitOptions{Interval: time.Minute})
+	if clierror.KindOf(err) != clierror.Timeout || build.Reason != "did not finish in time" {
+		t.Errorf("WaitForBuild() error = %v, reason = %q, want a timeout", err, build.Reason)
+	}
+}
+
+func TestWaitForBuildInterrupted(t *testing.T) {
+	api := &fakeAPI{workflows: []*cfapi.Workflow{{ID: "b1", Status: cfapi.StatusRunning}}}
+	s := newFakeService(api)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	builds, errs := s.RunPipelines(ctx, []string{"p1"}, &RunOptions{}, &WaitOptions{Interval: time.Minute}, 1)
+	if clierror.KindOf(errs[0]) != clierror.Interrupted || builds[0].Reason != "not started" {
+		t.Errorf("RunPipelines() error = %v, build = %+v, want an interrupted pipeline that did not start", errs[0], builds[0])
+	}
 }
@@ -0,0 +1,192 @@
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+)
+
+type (
+	// Build is a pipeline run started by the CLI
+	Build struct {
+		ID       string        `json:"id"`
+		Pipeline string        `json:"pipeline"`
+		URL      string        `json:"url"`
+		Status   string        `json:"status,omitempty"`
+		Duration time.Duration `json:"-"`
+		// Reason why the build did not succeed
+		Reason string `json:"reason,omitempty"`
+		// Steps as reported by the progress of the build
+		Steps []*BuildStep `json:"steps,omitempty"`
+	}
+
+	// BuildStep is a step of a build
+	BuildStep struct {
+		Name     string        `json:"name"`
+		Status   string        `json:"status"`
+		Duration time.Duration `json:"-"`
+	}
+
+	// Builds is a printable list of builds
+	Builds []*Build
+
+	// Workflows is a printable list of builds from the build history
+	Workflows []*cfapi.Workflow
+
+	// Pipelines is a printable list of pipelines
+	Pipelines []*cfapi.Pipeline
+
+	// PipelineDetails is a printable pipeline, the json and yaml formats show the whole pipeline
+	PipelineDetails struct {
+		*cfapi.Pipeline
+	}
+)
+
+// MarshalJSON writes the duration as duration_ms in milliseconds, like the TAP report
+func (b *Build) MarshalJSON() ([]byte, error) {
+	type build Build
+	return json.Marshal(&struct {
+		*build
+		DurationMS int64 `json:"duration_ms,omitempty"`
+	}{(*build)(b), milliseconds(b.Duration)})
+}
+
+// MarshalJSON writes the duration as duration_ms in milliseconds, like the TAP report
+func (s *BuildStep) MarshalJSON() ([]byte, error) {
+	type step BuildStep
+	return json.Marshal(&struct {
+		*step
+		DurationMS int64 `json:"duration_ms,omitempty"`
+	}{(*step)(s), milliseconds(s.Duration)})
+}
+
+// Header implements printer.Table
+func (b *Build) Header(wide bool) []string {
+	if wide {
+		return []string{"PIPELINE", "BUILD ID", "STATUS", "DURATION", "REASON", "URL"}
+	}
+	return []string{"PIPELINE", "BUILD ID", "STATUS", "DURATION", "REASON"}
+}
+
+// Rows implements printer.Table
+func (b *Build) Rows(wide bool) [][]string {
+	row := []string{b.Pipeline, b.ID, b.Status, formatDuration(b.Duration), b.Reason}
+	if wide {
+		row = append(row, b.URL)
+	}
+	return [][]string{row}
+}
+
+// Header implements printer.Table
+func (b Builds) Header(wide bool) []string {
+	return (&Build{}).Header(wide)
+}
+
+// Rows implements printer.Table
+func (b Builds) Rows(wide bool) [][]string {
+	rows := [][]string{}
+	for _, build := range b {
+		rows = append(rows, build.Rows(wide)...)
+	}
+	return rows
+}
+
+// Header implements printer.Table
+func (w Workflows) Header(wide bool) []string {
+	if wide {
+		return []string{"BUILD ID", "PIPELINE", "TRIGGER", "STATUS", "STARTED", "DURATION", "BRANCH", "REVISION", "RUNTIME ENVIRONMENT"}
+	}
+	return []string{"BUILD ID", "PIPELINE", "TRIGGER", "STATUS", "STARTED", "DURATION"}
+}
+
+// Rows implements printer.Table
+func (w Workflows) Rows(wide bool) [][]string {
+	rows := [][]string{}
+	for _, wf := range w {
+		row := []string{wf.ID, wf.PipelineName, wf.Trigger, wf.Status, formatTime(wf.Started), formatDuration(wf.Duration())}
+		if wide {
+			row = append(row, wf.BranchName, wf.Revision, wf.RuntimeEnvironment.Name)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Header implements printer.Table
+func (p Pipelines) Header(wide bool) []string {
+	if wide {
+		return []string{"NAME", "PROJECT", "RUNTIME ENVIRONMENT", "TRIGGERS", "ID", "TAGS", "CREATED", "UPDATED"}
+	}
+	return []string{"NAME", "PROJECT", "RUNTIME ENVIRONMENT", "UPDATED"}
+}
+
+// Rows implements printer.Table
+func (p Pipelines) Rows(wide bool) [][]string {
+	rows := [][]string{}
+	for _, pipeline := range p {
+		m := pipeline.Metadata
+		if wide {
+			rows = append(rows, []string{m.Name, m.Project, pipeline.Spec.RuntimeEnvironment.Name, formatTriggers(pipeline.Spec.Triggers), m.ID, strings.Join(m.Labels.Tags, ","), formatTime(m.CreatedAt), formatTime(m.UpdatedAt)})
+		} else {
+			rows = append(rows, []string{m.Name, m.Project, pipeline.Spec.RuntimeEnvironment.Name, formatTime(m.UpdatedAt)})
+		}
+	}
+	return rows
+}
+
+// Header implements printer.Table
+func (p *PipelineDetails) Header(wide bool) []string {
+	if wide {
+		return []string{"NAME", "PROJECT", "RUNTIME ENVIRONMENT", "TRIGGERS", "STEPS", "ID", "TAGS", "UPDATED"}
+	}
+	return []string{"NAME", "PROJECT", "RUNTIME ENVIRONMENT", "TRIGGERS", "STEPS"}
+}
+
+// Rows implements printer.Table
+func (p *PipelineDetails) Rows(wide bool) [][]string {
+	m := p.Metadata
+	steps := []string{}
+	for name := range p.Spec.Steps {
+		steps = append(steps, name)
+	}
+	sort.Strings(steps)
+	row := []string{m.Name, m.Project, p.Spec.RuntimeEnvironment.Name, formatTriggers(p.Spec.Triggers), strings.Join(steps, ",")}
+	if wide {
+		row = append(row, m.ID, strings.Join(m.Labels.Tags, ","), formatTime(m.UpdatedAt))
+	}
+	return [][]string{row}
+}
+
+func formatTriggers(triggers []*cfapi.Trigger) string {
+	formatted := []string{}
+	for _, t := range triggers {
+		if t.Repo != "" {
+			formatted = append(formatted, fmt.Sprintf("%s:%s(%s)", t.Type, t.Name, t.Repo))
+		} else {
+			formatted = append(formatted, fmt.Sprintf("%s:%s", t.Type, t.Name))
+		}
+	}
+	return strings.Join(formatted, ",")
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func milliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.Round(time.Second).String()
+}
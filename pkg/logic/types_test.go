@@ -0,0 +1,75 @@
+package logic
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/printer"
+)
+
+func TestBuildsOutput(t *testing.T) {
+	builds := Builds{
+		{
+			ID:       "1",
+			Pipeline: "demo/hello",
+			Status:   "success",
+			Duration: 83*time.Second + 250*time.Millisecond,
+			Steps:    []*BuildStep{{Name: "main", Status: "success", Duration: 1500 * time.Millisecond}},
+		},
+		{ID: "2", Pipeline: "demo/build", Status: "pending"},
+	}
+	tests := map[string]string{
+		printer.FormatJSON: `[
+  {
+    "id": "1",
+    "pipeline": "demo/hello",
+    "url": "",
+    "status": "success",
+    "steps": [
+      {
+        "name": "main",
+        "status": "success",
+        "duration_ms": 1500
+      }
+    ],
+    "duration_ms": 83250
+  },
+  {
+    "id": "2",
+    "pipeline": "demo/build",
+    "url": "",
+    "status": "pending"
+  }
+]
+`,
+		printer.FormatYAML: `- duration_ms: 83250
+  id: "1"
+  pipeline: demo/hello
+  status: success
+  steps:
+  - duration_ms: 1500
+    name: main
+    status: success
+  url: ""
+- id: "2"
+  pipeline: demo/build
+  status: pending
+  url: ""
+`,
+		"jsonpath={[0].duration_ms}": "83250\n",
+	}
+	for format, expected := range tests {
+		buf := &bytes.Buffer{}
+		p, err := printer.New(format, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Print(builds); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if buf.String() != expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", format, expected, buf.String())
+		}
+	}
+}
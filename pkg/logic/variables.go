@@ -0,0 +1,110 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"gopkg.in/yaml.v2"
+)
+
+// ParseVariables merges the variable files and the KEY=VALUE pairs into one map,
+// files are read in order and the pairs override them.
+// .yaml, .yml and .json files hold a map, any other file is read as .env
+func ParseVariables(pairs []string, files []string) (map[string]string, error) {
+	vars := map[string]string{}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, clierror.Errorf(clierror.Config, "Failed to read variables file: %v", err)
+		}
+		var fileVars map[string]string
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml", ".json":
+			fileVars, err = parseStructuredVariables(data)
+		default:
+			fileVars, err = parseEnvVariables(data)
+		}
+		if err != nil {
+			return nil, clierror.Errorf(clierror.Config, "Failed to parse variables file %s: %v", file, err)
+		}
+		for k, v := range fileVars {
+			vars[k] = v
+		}
+	}
+	for _, pair := range pairs {
+		k, v, err := splitVariable(pair)
+		if err != nil {
+			return nil, clierror.New(clierror.Usage, err)
+		}
+		vars[k] = v
+	}
+	return vars, nil
+}
+
+// scalar keeps the text of a yaml value as it is written in the file: 12345678 is not 1.2345678e+07,
+// 1.10 is not 1.1 and yes is not true
+type scalar struct {
+	text     string
+	isScalar bool
+}
+
+func (s *scalar) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// a string takes the text of any scalar, a map or a list is not a scalar
+	s.isScalar = unmarshal(&s.text) == nil
+	return nil
+}
+
+func parseStructuredVariables(data []byte) (map[string]string, error) {
+	raw := map[string]*scalar{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	vars := map[string]string{}
+	for k, v := range raw {
+		switch {
+		case v == nil:
+			vars[k] = ""
+		case !v.isScalar:
+			return nil, fmt.Errorf("value of %s is not a scalar", k)
+		default:
+			vars[k] = v.text
+		}
+	}
+	return vars, nil
+}
+
+func parseEnvVariables(data []byte) (map[string]string, error) {
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+		k, v, err := splitVariable(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		vars[k] = v
+	}
+	return vars, scanner.Err()
+}
+
+func splitVariable(pair string) (string, string, error) {
+	i := strings.Index(pair, "=")
+	if i <= 0 {
+		return "", "", fmt.Errorf("Variable %q is not in KEY=VALUE format", pair)
+	}
+	return strings.TrimSpace(pair[:i]), pair[i+1:], nil
+}
@@ -0,0 +1,98 @@
+package logic
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseVariables(t *testing.T) {
+	dir, err := ioutil.TempDir("", "variables")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	yamlFile := filepath.Join(dir, "vars.yaml")
+	ioutil.WriteFile(yamlFile, []byte("IMAGE: alpine\nREPLICAS: 3\nDEBUG: true\n"), 0644)
+	jsonFile := filepath.Join(dir, "vars.json")
+	ioutil.WriteFile(jsonFile, []byte(`{"IMAGE": "busybox", "TAG": "1.0"}`), 0644)
+	envFile := filepath.Join(dir, ".env")
+	ioutil.WriteFile(envFile, []byte("# comment\nexport TAG=\"2.0\"\n\nURL=http://host/?a=b\n"), 0644)
+
+	vars, err := ParseVariables([]string{"DEBUG=false", "EMPTY="}, []string{yamlFile, jsonFile, envFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"IMAGE":    "busybox",
+		"REPLICAS": "3",
+		"DEBUG":    "false",
+		"TAG":      "2.0",
+		"URL":      "http://host/?a=b",
+		"EMPTY":    "",
+	}
+	if len(vars) != len(expected) {
+		t.Errorf("expected %v, got %v", expected, vars)
+	}
+	for k, v := range expected {
+		if vars[k] != v {
+			t.Errorf("%s: expected %q, got %q", k, v, vars[k])
+		}
+	}
+}
+
+func TestParseVariablesLiteral(t *testing.T) {
+	dir, err := ioutil.TempDir("", "variables")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	yamlFile := filepath.Join(dir, "vars.yml")
+	ioutil.WriteFile(yamlFile, []byte("BUILD: 12345678\nBIG: 123456789012345678901\nVERSION: 1.10\nPUSH: yes\nDEPLOY: no\nMODE: \"off\"\nEMPTY:\nOCTAL: 0755\n"), 0644)
+	jsonFile := filepath.Join(dir, "vars.json")
+	ioutil.WriteFile(jsonFile, []byte(`{"REPLICAS": 10000000, "RATIO": 2.50, "FLAG": true}`), 0644)
+
+	vars, err := ParseVariables(nil, []string{yamlFile, jsonFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"BUILD":    "12345678",
+		"BIG":      "123456789012345678901",
+		"VERSION":  "1.10",
+		"PUSH":     "yes",
+		"DEPLOY":   "no",
+		"MODE":     "off",
+		"EMPTY":    "",
+		"OCTAL":    "0755",
+		"REPLICAS": "10000000",
+		"RATIO":    "2.50",
+		"FLAG":     "true",
+	}
+	if len(vars) != len(expected) {
+		t.Errorf("expected %v, got %v", expected, vars)
+	}
+	for k, v := range expected {
+		if vars[k] != v {
+			t.Errorf("%s: expected %q, got %q", k, v, vars[k])
+		}
+	}
+
+	nested := filepath.Join(dir, "nested.yaml")
+	ioutil.WriteFile(nested, []byte("IMAGE:\n  name: alpine\n"), 0644)
+	if _, err := ParseVariables(nil, []string{nested}); err == nil || err.Error() != "Failed to parse variables file "+nested+": value of IMAGE is not a scalar" {
+		t.Errorf("expected a not a scalar error, got %v", err)
+	}
+}
+
+func TestParseVariablesInvalid(t *testing.T) {
+	if _, err := ParseVariables([]string{"NOVALUE"}, nil); err == nil {
+		t.Error("expected an error for a variable without =")
+	}
+	if _, err := ParseVariables(nil, []string{"/does/not/exist.env"}); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,107 @@
+package mockapi
+
+import (
+	"time"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+)
+
+// build is a pipeline run, its status is computed from the time that passed since it started
+type build struct {
+	id                 string
+	pipeline           string
+	branch             string
+	revision           string
+	trigger            string
+	runtimeEnvironment string
+	variables          map[string]string
+	outcome            *Outcome
+
+	created time.Time
+	// terminated, approved and denied are the times of the actions on the build
+	terminated time.Time
+	approved   time.Time
+	denied     time.Time
+}
+
+// state returns the build and its steps at now
+func (b *build) state(now time.Time) (*cfapi.Workflow, *cfapi.Progress) {
+	wf := &cfapi.Workflow{
+		ID:           b.id,
+		PipelineName: b.pipeline,
+		BranchName:   b.branch,
+		Revision:     b.revision,
+		Trigger:      b.trigger,
+		Created:      b.created,
+		Started:      b.created,
+		Progress:     b.id,
+	}
+	wf.RuntimeEnvironment.Name = b.runtimeEnvironment
+
+	if !b.terminated.IsZero() && now.After(b.terminated) {
+		now = b.terminated
+	}
+	end := b.created.Add(b.outcome.Duration.Duration)
+	steps := b.steps(now)
+
+	switch {
+	case !b.terminated.IsZero():
+		wf.Status = cfapi.StatusTerminated
+		wf.Finished = b.terminated
+	case now.Before(end):
+		wf.Status = cfapi.StatusRunning
+	case b.outcome.Status == cfapi.StatusPendingApproval && !b.approved.IsZero():
+		wf.Status = cfapi.StatusSuccess
+		wf.Finished = b.approved
+	case b.outcome.Status == cfapi.StatusPendingApproval && !b.denied.IsZero():
+		wf.Status = cfapi.StatusDenied
+		wf.Finished = b.denied
+	case b.outcome.Status == cfapi.StatusPendingApproval:
+		wf.Status = cfapi.StatusPendingApproval
+	default:
+		wf.Status = b.outcome.Status
+		wf.Finished = end
+	}
+	if wf.Status == cfapi.StatusTerminated {
+		for _, step := range steps {
+			if !cfapi.IsTerminal(step.Status) && step.Status != cfapi.StatusPending {
+				step.Status = cfapi.StatusTerminated
+				step.FinishTimeStamp = b.terminated.Unix()
+			}
+		}
+	}
+	return wf, &cfapi.Progress{ID: b.id, Status: wf.Status, Steps: steps}
+}
+
+// steps splits the duration of the build evenly between the steps, a running step has part of its logs
+func (b *build) steps(now time.Time) []*cfapi.Step {
+	steps := []*cfapi.Step{}
+	n := len(b.outcome.Steps)
+	if n == 0 {
+		return steps
+	}
+	each := b.outcome.Duration.Duration / time.Duration(n)
+	for i, s := range b.outcome.Steps {
+		start := b.created.Add(time.Duration(i) * each)
+		end := start.Add(each)
+		step := &cfapi.Step{Name: s.Name, Title: s.Name, Status: cfapi.StatusPending}
+		switch {
+		case now.Before(start):
+		case now.Before(end):
+			step.Status = cfapi.StatusRunning
+			step.CreationTimeStamp = start.Unix()
+			shown := int(float64(len(s.Logs)) * float64(now.Sub(start)) / float64(each))
+			step.Logs = append([]string{}, s.Logs[:shown]...)
+		default:
+			step.Status = s.Status
+			step.CreationTimeStamp = start.Unix()
+			step.FinishTimeStamp = end.Unix()
+			step.Logs = append([]string{}, s.Logs...)
+		}
+		if step.Logs == nil {
+			step.Logs = []string{}
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
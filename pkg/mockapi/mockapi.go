@@ -0,0 +1,612 @@
+package mockapi
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codefresh-io/go-sdk/pkg/codefresh"
+	"github.com/codefresh-io/venona/venonactl/pkg/logger"
+	log "github.com/inconshreveable/log15"
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+)
+
+// DefaultToken is the token the mock accepts when Options.Token is empty
+const DefaultToken = "mock-token"
+
+type (
+	// Options of the mock server
+	Options struct {
+		// Token is the value of the Authorization header the mock accepts, default is DefaultToken
+		Token string
+		// Scenario is the initial state and the build outcomes, default is an empty account
+		// where every build succeeds
+		Scenario *Scenario
+		Logger   logger.Logger
+	}
+
+	// Server serves the part of the Codefresh API the CLI uses from memory, it is an http.Handler
+	Server struct {
+		token    string
+		scenario *Scenario
+		logger   logger.Logger
+		routes   []*route
+		now      func() time.Time
+
+		mu                  sync.Mutex
+		pipelines           map[string]map[string]interface{}
+		runtimeEnvironments map[string]*codefresh.RuntimeEnvironment
+		defaultRE           string
+		builds              map[string]*build
+		buildOrder          []string
+		tokens              []string
+		nextID              int
+	}
+
+	route struct {
+		method  string
+		pattern *regexp.Regexp
+		handler func(r *http.Request, params []string) (int, interface{})
+	}
+
+	errorResponse struct {
+		Message string `json:"message"`
+	}
+
+	// text is a response body that is written as is
+	text string
+)
+
+// New creates the mock server
+func New(opt *Options) *Server {
+	s := &Server{
+		token:               opt.Token,
+		scenario:            opt.Scenario,
+		logger:              opt.Logger,
+		now:                 time.Now,
+		pipelines:           map[string]map[string]interface{}{},
+		runtimeEnvironments: map[string]*codefresh.RuntimeEnvironment{},
+		builds:              map[string]*build{},
+	}
+	if s.token == "" {
+		s.token = DefaultToken
+	}
+	if s.scenario == nil {
+		s.scenario = &Scenario{}
+	}
+	if s.logger == nil {
+		l := log.New()
+		l.SetHandler(log.DiscardHandler())
+		s.logger = l
+	}
+	for _, p := range s.scenario.Pipelines {
+		if _, err := s.putPipeline(p); err != nil {
+			s.logger.Warn("Skipping pipeline of the scenario", "Error", err)
+		}
+	}
+	for _, re := range s.scenario.RuntimeEnvironments {
+		s.addRuntimeEnvironment(re.Name, re.Offline)
+	}
+	s.routes = []*route{
+		{"GET", regexp.MustCompile(`^/api/contexts$`), s.listContexts},
+		{"GET", regexp.MustCompile(`^/api/contexts/([^/]+)$`), s.getContext},
+		{"GET", regexp.MustCompile(`^/api/repos/([^/]+)/([^/]+)/branch/([^/]+)$`), s.getBranch},
+		{"GET", regexp.MustCompile(`^/api/pipelines$`), s.listPipelines},
+		{"POST", regexp.MustCompile(`^/api/pipelines$`), s.createPipeline},
+		{"POST", regexp.MustCompile(`^/api/pipelines/run/([^/]+)$`), s.runPipeline},
+		{"GET", regexp.MustCompile(`^/api/pipelines/([^/]+)$`), s.getPipeline},
+		{"PUT", regexp.MustCompile(`^/api/pipelines/([^/]+)$`), s.replacePipeline},
+		{"DELETE", regexp.MustCompile(`^/api/pipelines/([^/]+)$`), s.deletePipeline},
+		{"GET", regexp.MustCompile(`^/api/builds/rebuild/([^/]+)$`), s.restartBuild},
+		{"POST", regexp.MustCompile(`^/api/builds/([^/]+)/terminate$`), s.terminateBuild},
+		{"GET", regexp.MustCompile(`^/api/builds/([^/]+)$`), s.getBuild},
+		{"GET", regexp.MustCompile(`^/api/workflow$`), s.listBuilds},
+		{"POST", regexp.MustCompile(`^/api/workflow/([^/]+)/pending-approval/(approve|deny)$`), s.approveBuild},
+		{"GET", regexp.MustCompile(`^/api/progress/([^/]+)$`), s.getProgress},
+		{"GET", regexp.MustCompile(`^/api/runtime-environments$`), s.listRuntimeEnvironments},
+		{"PUT", regexp.MustCompile(`^/api/runtime-environments/default/([^/]+)$`), s.setDefaultRuntimeEnvironment},
+		{"GET", regexp.MustCompile(`^/api/runtime-environments/([^/]+)$`), s.getRuntimeEnvironment},
+		{"DELETE", regexp.MustCompile(`^/api/runtime-environments/([^/]+)$`), s.deleteRuntimeEnvironment},
+		{"POST", regexp.MustCompile(`^/api/custom_clusters/register$`), s.registerCluster},
+		{"POST", regexp.MustCompile(`^/api/custom_clusters/validate$`), s.validateCluster},
+		{"POST", regexp.MustCompile(`^/api/custom_clusters/signServerCerts$`), s.signCertificates},
+		{"POST", regexp.MustCompile(`^/api/auth/key$`), s.createToken},
+		{"GET", regexp.MustCompile(`^/api/auth/keys$`), s.listTokens},
+	}
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status, body := s.handle(r)
+	s.logger.Debug("Mock API request", "Method", r.Method, "Path", r.URL.Path, "Status", status)
+
+	switch b := body.(type) {
+	case []byte:
+		w.Header().Set("Content-Type", "application/zip")
+		w.WriteHeader(status)
+		w.Write(b)
+		return
+	case text:
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(status)
+		w.Write([]byte(b))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if body != nil {
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+func (s *Server) handle(r *http.Request) (int, interface{}) {
+	if r.Header.Get("Authorization") != s.token {
+		return http.StatusUnauthorized, &errorResponse{"Invalid token"}
+	}
+	// match the escaped path, pipeline names are escaped project%2Fpipeline
+	path := r.URL.EscapedPath()
+	found := false
+	for _, rt := range s.routes {
+		m := rt.pattern.FindStringSubmatch(path)
+		if m == nil {
+			continue
+		}
+		found = true
+		if rt.method != r.Method {
+			continue
+		}
+		params := []string{}
+		for _, p := range m[1:] {
+			unescaped, err := url.PathUnescape(p)
+			if err != nil {
+				return http.StatusBadRequest, &errorResponse{err.Error()}
+			}
+			params = append(params, unescaped)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return rt.handler(r, params)
+	}
+	if found {
+		return http.StatusMethodNotAllowed, &errorResponse{fmt.Sprintf("%s is not supported on %s", r.Method, r.URL.Path)}
+	}
+	return http.StatusNotFound, &errorResponse{fmt.Sprintf("%s is not served by the mock API", r.URL.Path)}
+}
+
+func notFound(format string, a ...interface{}) (int, interface{}) {
+	return http.StatusNotFound, &errorResponse{fmt.Sprintf(format, a...)}
+}
+
+func badRequest(format string, a ...interface{}) (int, interface{}) {
+	return http.StatusBadRequest, &errorResponse{fmt.Sprintf(format, a...)}
+}
+
+func decodeBody(r *http.Request, target interface{}) error {
+	if r.Body == nil {
+		return nil
+	}
+	err := json.NewDecoder(r.Body).Decode(target)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) newID() string {
+	s.nextID++
+	return fmt.Sprintf("%024x", s.nextID)
+}
+
+func (s *Server) gitContexts() []string {
+	if len(s.scenario.GitContexts) == 0 {
+		return []string{"github"}
+	}
+	return s.scenario.GitContexts
+}
+
+func (s *Server) listContexts(r *http.Request, params []string) (int, interface{}) {
+	contexts := []interface{}{}
+	for _, name := range s.gitContexts() {
+		contexts = append(contexts, gitContext(name))
+	}
+	return http.StatusOK, contexts
+}
+
+func (s *Server) getContext(r *http.Request, params []string) (int, interface{}) {
+	for _, name := range s.gitContexts() {
+		if name == params[0] {
+			return http.StatusOK, gitContext(name)
+		}
+	}
+	return notFound("Context %s was not found", params[0])
+}
+
+func gitContext(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"name": name},
+		"spec":     map[string]interface{}{"type": "git.github"},
+	}
+}
+
+func (s *Server) getBranch(r *http.Request, params []string) (int, interface{}) {
+	repo, branch := params[0]+"/"+params[1], params[2]
+	if branches, ok := s.scenario.Repos[repo]; ok {
+		found := false
+		for _, b := range branches {
+			found = found || b == branch
+		}
+		if !found {
+			return notFound("Branch %s was not found in %s", branch, repo)
+		}
+	}
+	b := &cfapi.Branch{Name: branch}
+	b.Commit.Sha = fmt.Sprintf("%040x", len(repo)+len(branch))
+	return http.StatusOK, b
+}
+
+// putPipeline stores the pipeline document, the managed metadata is set like Codefresh does
+func (s *Server) putPipeline(p map[string]interface{}) (map[string]interface{}, error) {
+	metadata, _ := p["metadata"].(map[string]interface{})
+	if metadata == nil {
+		return nil, fmt.Errorf("Pipeline has no metadata")
+	}
+	name, _ := metadata["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("Pipeline has no name")
+	}
+	now := s.now().UTC().Format(time.RFC3339)
+	if old, ok := s.pipelines[name]; ok {
+		oldMetadata := old["metadata"].(map[string]interface{})
+		metadata["id"] = oldMetadata["id"]
+		metadata["created_at"] = oldMetadata["created_at"]
+	} else {
+		metadata["id"] = s.newID()
+		metadata["created_at"] = now
+	}
+	metadata["updated_at"] = now
+	if i := strings.Index(name, "/"); i >= 0 {
+		metadata["project"] = name[:i]
+	}
+	if _, ok := p["spec"]; !ok {
+		p["spec"] = map[string]interface{}{}
+	}
+	s.pipelines[name] = p
+	return p, nil
+}
+
+func (s *Server) listPipelines(r *http.Request, params []string) (int, interface{}) {
+	names := []string{}
+	for name := range s.pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = len(names)
+	}
+	docs := []interface{}{}
+	for i := offset; i < len(names) && i < offset+limit; i++ {
+		docs = append(docs, s.pipelines[names[i]])
+	}
+	return http.StatusOK, map[string]interface{}{"docs": docs, "count": len(names)}
+}
+
+func (s *Server) getPipeline(r *http.Request, params []string) (int, interface{}) {
+	p, ok := s.pipelines[params[0]]
+	if !ok {
+		return notFound("Pipeline %s was not found", params[0])
+	}
+	return http.StatusOK, p
+}
+
+func (s *Server) createPipeline(r *http.Request, params []string) (int, interface{}) {
+	p := map[string]interface{}{}
+	if err := decodeBody(r, &p); err != nil {
+		return badRequest("Invalid pipeline: %v", err)
+	}
+	if metadata, ok := p["metadata"].(map[string]interface{}); ok {
+		if _, exists := s.pipelines[fmt.Sprint(metadata["name"])]; exists {
+			return badRequest("Pipeline %s already exists", metadata["name"])
+		}
+	}
+	created, err := s.putPipeline(p)
+	if err != nil {
+		return badRequest("%v", err)
+	}
+	return http.StatusOK, created
+}
+
+func (s *Server) replacePipeline(r *http.Request, params []string) (int, interface{}) {
+	if _, ok := s.pipelines[params[0]]; !ok {
+		return notFound("Pipeline %s was not found", params[0])
+	}
+	p := map[string]interface{}{}
+	if err := decodeBody(r, &p); err != nil {
+		return badRequest("Invalid pipeline: %v", err)
+	}
+	replaced, err := s.putPipeline(p)
+	if err != nil {
+		return badRequest("%v", err)
+	}
+	return http.StatusOK, replaced
+}
+
+func (s *Server) deletePipeline(r *http.Request, params []string) (int, interface{}) {
+	if _, ok := s.pipelines[params[0]]; !ok {
+		return notFound("Pipeline %s was not found", params[0])
+	}
+	delete(s.pipelines, params[0])
+	return http.StatusOK, map[string]interface{}{}
+}
+
+func (s *Server) runPipeline(r *http.Request, params []string) (int, interface{}) {
+	name := params[0]
+	p, ok := s.pipelines[name]
+	if !ok {
+		return notFound("Pipeline %s was not found", name)
+	}
+	body := struct {
+		Branch             string            `json:"branch"`
+		SHA                string            `json:"sha"`
+		Trigger            string            `json:"trigger"`
+		Variables          map[string]string `json:"variables"`
+		RuntimeEnvironment string            `json:"runtimeEnvironment"`
+	}{}
+	if err := decodeBody(r, &body); err != nil {
+		return badRequest("Invalid run options: %v", err)
+	}
+	re := body.RuntimeEnvironment
+	if re == "" {
+		spec, _ := p["spec"].(map[string]interface{})
+		if specRE, ok := spec["runtimeEnvironment"].(map[string]interface{}); ok {
+			re, _ = specRE["name"].(string)
+		}
+	}
+	if re == "" {
+		re = s.defaultRE
+	}
+	if re != "" {
+		if _, ok := s.runtimeEnvironments[re]; !ok {
+			return badRequest("Runtime environment %s was not found", re)
+		}
+	}
+	b := &build{
+		id:                 s.newID(),
+		pipeline:           name,
+		branch:             body.Branch,
+		revision:           body.SHA,
+		trigger:            body.Trigger,
+		runtimeEnvironment: re,
+		variables:          body.Variables,
+		outcome:            s.scenario.outcome(name),
+		created:            s.now(),
+	}
+	s.addBuild(b)
+	s.logger.Info("Mock build started", "Pipeline", name, "Build-ID", b.id, "Outcome", b.outcome.Status)
+	return http.StatusOK, b.id
+}
+
+func (s *Server) addBuild(b *build) {
+	s.builds[b.id] = b
+	s.buildOrder = append(s.buildOrder, b.id)
+}
+
+func (s *Server) getBuild(r *http.Request, params []string) (int, interface{}) {
+	b, ok := s.builds[params[0]]
+	if !ok {
+		return notFound("Build %s was not found", params[0])
+	}
+	wf, _ := b.state(s.now())
+	return http.StatusOK, wf
+}
+
+func (s *Server) getProgress(r *http.Request, params []string) (int, interface{}) {
+	b, ok := s.builds[params[0]]
+	if !ok {
+		return notFound("Progress %s was not found", params[0])
+	}
+	_, progress := b.state(s.now())
+	return http.StatusOK, progress
+}
+
+func (s *Server) listBuilds(r *http.Request, params []string) (int, interface{}) {
+	qs := r.URL.Query()
+	pipelineName := ""
+	if id := qs.Get("pipeline"); id != "" {
+		for name, p := range s.pipelines {
+			if p["metadata"].(map[string]interface{})["id"] == id {
+				pipelineName = name
+			}
+		}
+		if pipelineName == "" {
+			return http.StatusOK, map[string]interface{}{"workflows": map[string]interface{}{"docs": []interface{}{}, "total": 0}}
+		}
+	}
+	matched := []*cfapi.Workflow{}
+	now := s.now()
+	// newest first
+	for i := len(s.buildOrder) - 1; i >= 0; i-- {
+		wf, _ := s.builds[s.buildOrder[i]].state(now)
+		if pipelineName != "" && wf.PipelineName != pipelineName {
+			continue
+		}
+		if status := qs.Get("status"); status != "" && wf.Status != status {
+			continue
+		}
+		if branch := qs.Get("branchName"); branch != "" && wf.BranchName != branch {
+			continue
+		}
+		matched = append(matched, wf)
+	}
+	limit, err := strconv.Atoi(qs.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = len(matched)
+	}
+	page, err := strconv.Atoi(qs.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	docs := []*cfapi.Workflow{}
+	for i := (page - 1) * limit; i < len(matched) && i < page*limit; i++ {
+		docs = append(docs, matched[i])
+	}
+	return http.StatusOK, map[string]interface{}{"workflows": map[string]interface{}{"docs": docs, "total": len(matched)}}
+}
+
+func (s *Server) terminateBuild(r *http.Request, params []string) (int, interface{}) {
+	b, ok := s.builds[params[0]]
+	if !ok {
+		return notFound("Build %s was not found", params[0])
+	}
+	if wf, _ := b.state(s.now()); cfapi.IsTerminal(wf.Status) {
+		return badRequest("Build %s already finished", b.id)
+	}
+	b.terminated = s.now()
+	s.logger.Info("Mock build terminated", "Build-ID", b.id)
+	return http.StatusOK, map[string]interface{}{}
+}
+
+func (s *Server) restartBuild(r *http.Request, params []string) (int, interface{}) {
+	old, ok := s.builds[params[0]]
+	if !ok {
+		return notFound("Build %s was not found", params[0])
+	}
+	b := *old
+	b.id = s.newID()
+	b.created = s.now()
+	b.terminated, b.approved, b.denied = time.Time{}, time.Time{}, time.Time{}
+	s.addBuild(&b)
+	s.logger.Info("Mock build restarted", "Pipeline", b.pipeline, "Build-ID", b.id, "Restarted-Build-ID", old.id)
+	return http.StatusOK, b.id
+}
+
+func (s *Server) approveBuild(r *http.Request, params []string) (int, interface{}) {
+	b, ok := s.builds[params[0]]
+	if !ok {
+		return notFound("Build %s was not found", params[0])
+	}
+	if wf, _ := b.state(s.now()); wf.Status != cfapi.StatusPendingApproval {
+		return badRequest("Build %s is not pending approval", b.id)
+	}
+	if params[1] == "approve" {
+		b.approved = s.now()
+	} else {
+		b.denied = s.now()
+	}
+	return http.StatusOK, map[string]interface{}{}
+}
+
+func (s *Server) addRuntimeEnvironment(name string, offline bool) *codefresh.RuntimeEnvironment {
+	re := &codefresh.RuntimeEnvironment{Version: 1}
+	re.Metadata.Name = name
+	re.Status.Message = "online"
+	if offline {
+		re.Status.Message = "offline"
+	}
+	re.Status.UpdatedAt = s.now()
+	s.runtimeEnvironments[name] = re
+	return re
+}
+
+func (s *Server) listRuntimeEnvironments(r *http.Request, params []string) (int, interface{}) {
+	names := []string{}
+	for name := range s.runtimeEnvironments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	res := []*codefresh.RuntimeEnvironment{}
+	for _, name := range names {
+		res = append(res, s.runtimeEnvironments[name])
+	}
+	return http.StatusOK, res
+}
+
+func (s *Server) getRuntimeEnvironment(r *http.Request, params []string) (int, interface{}) {
+	re, ok := s.runtimeEnvironments[params[0]]
+	if !ok {
+		return notFound("Runtime environment %s was not found", params[0])
+	}
+	return http.StatusOK, re
+}
+
+func (s *Server) deleteRuntimeEnvironment(r *http.Request, params []string) (int, interface{}) {
+	if _, ok := s.runtimeEnvironments[params[0]]; !ok {
+		return notFound("Runtime environment %s was not found", params[0])
+	}
+	delete(s.runtimeEnvironments, params[0])
+	return http.StatusOK, true
+}
+
+func (s *Server) setDefaultRuntimeEnvironment(r *http.Request, params []string) (int, interface{}) {
+	if _, ok := s.runtimeEnvironments[params[0]]; !ok {
+		return notFound("Runtime environment %s was not found", params[0])
+	}
+	s.defaultRE = params[0]
+	return http.StatusCreated, true
+}
+
+func (s *Server) registerCluster(r *http.Request, params []string) (int, interface{}) {
+	body := struct {
+		ClusterName string `json:"clusterName"`
+		Namespace   string `json:"namespace"`
+	}{}
+	if err := decodeBody(r, &body); err != nil || body.ClusterName == "" || body.Namespace == "" {
+		return badRequest("clusterName and namespace are required")
+	}
+	re := s.addRuntimeEnvironment(fmt.Sprintf("%s/%s", body.ClusterName, body.Namespace), false)
+	s.logger.Info("Mock runtime environment registered", "Runtime-Environment", re.Metadata.Name)
+	return http.StatusOK, re
+}
+
+func (s *Server) validateCluster(r *http.Request, params []string) (int, interface{}) {
+	return http.StatusOK, map[string]interface{}{}
+}
+
+// signCertificates returns the zip the runtime environment installation expects, the certificates are placeholders
+func (s *Server) signCertificates(r *http.Request, params []string) (int, interface{}) {
+	buf := &bytes.Buffer{}
+	z := zip.NewWriter(buf)
+	for _, name := range []string{"cf-ca.pem", "cf-server-cert.pem"} {
+		f, err := z.Create(name)
+		if err != nil {
+			return http.StatusInternalServerError, &errorResponse{err.Error()}
+		}
+		fmt.Fprintf(f, "-----BEGIN CERTIFICATE-----\nmock %s\n-----END CERTIFICATE-----\n", name)
+	}
+	if err := z.Close(); err != nil {
+		return http.StatusInternalServerError, &errorResponse{err.Error()}
+	}
+	return http.StatusOK, buf.Bytes()
+}
+
+func (s *Server) createToken(r *http.Request, params []string) (int, interface{}) {
+	body := struct {
+		Name string `json:"name"`
+	}{}
+	if err := decodeBody(r, &body); err != nil {
+		return badRequest("Invalid token: %v", err)
+	}
+	s.tokens = append(s.tokens, body.Name)
+	// the SDK reads the token as text
+	return http.StatusOK, text(fmt.Sprintf("mock.%s", s.newID()))
+}
+
+func (s *Server) listTokens(r *http.Request, params []string) (int, interface{}) {
+	tokens := []interface{}{}
+	for _, name := range s.tokens {
+		tokens = append(tokens, map[string]interface{}{"name": name})
+	}
+	return http.StatusOK, tokens
+}
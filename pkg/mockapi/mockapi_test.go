@@ -0,0 +1,396 @@
+package mockapi
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/codefresh-io/go-sdk/pkg/codefresh"
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+	"github.com/sharon-vendrov/sharoncli/pkg/clierror"
+	"github.com/sharon-vendrov/sharoncli/pkg/logic"
+)
+
+// newService starts the mock and returns a service that uses it, stop stops the mock
+func newService(scenario *Scenario, token string) (s *logic.Service, mock *Server, stop func()) {
+	mock = New(&Options{Scenario: scenario})
+	server := httptest.NewServer(mock)
+	return logic.New(&logic.Options{
+		Codefresh: codefresh.New(&codefresh.ClientOptions{Host: server.URL, Auth: codefresh.AuthOptions{Token: token}}),
+		Host:      server.URL,
+		Token:     token,
+	}), mock, server.Close
+}
+
+func testScenario(status string) *Scenario {
+	return &Scenario{
+		Pipelines: []map[string]interface{}{
+			{
+				"metadata": map[string]interface{}{"name": "default/build"},
+				"spec": map[string]interface{}{
+					"runtimeEnvironment": map[string]interface{}{"name": "kind/default"},
+					"triggers":           []interface{}{map[string]interface{}{"name": "push", "type": "git", "repo": "owner/repo", "context": "github"}},
+				},
+			},
+		},
+		RuntimeEnvironments: []*RuntimeEnvironment{{Name: "kind/default"}, {Name: "kind/offline", Offline: true}},
+		Repos:               map[string][]string{"owner/repo": {"master"}},
+		Builds: []*Outcome{{
+			Pipeline: "default/build",
+			Status:   status,
+			Duration: Duration{20 * time.Millisecond},
+			Steps: []*Step{
+				{Name: "clone", Status: cfapi.StatusSuccess, Logs: []string{"cloning"}},
+				{Name: "test", Status: status, Logs: []string{"testing"}},
+			},
+		}},
+	}
+}
+
+var waitOptions = &logic.WaitOptions{Interval: 5 * time.Millisecond}
+
+func TestRunPipeline(t *testing.T) {
+	tests := []struct {
+		status     string
+		wantErr    clierror.Kind
+		wantReason string
+	}{
+		{status: cfapi.StatusSuccess},
+		{status: cfapi.StatusError, wantErr: clierror.PipelineFailed, wantReason: "step test failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			s, _, stop := newService(testScenario(tt.status), DefaultToken)
+			defer stop()
+			build, err := s.ExecutePipeline(context.Background(), "default/build", &logic.RunOptions{Branch: "master"})
+			if err != nil {
+				t.Fatalf("ExecutePipeline() error = %v", err)
+			}
+			err = s.WaitForBuild(context.Background(), build, waitOptions)
+			if tt.wantErr == 0 && err != nil || tt.wantErr != 0 && clierror.KindOf(err) != tt.wantErr {
+				t.Fatalf("WaitForBuild() error = %v, want kind %v", err, tt.wantErr)
+			}
+			if build.Status != tt.status || build.Reason != tt.wantReason || len(build.Steps) != 2 {
+				t.Errorf("WaitForBuild() build = %+v", build)
+			}
+		})
+	}
+}
+
+func TestRunPipelineValidation(t *testing.T) {
+	s, _, stop := newService(testScenario(cfapi.StatusSuccess), DefaultToken)
+	defer stop()
+	tests := []struct {
+		name  string
+		check func() error
+		want  clierror.Kind
+	}{
+		{name: "missing branch", check: func() error {
+			_, err := s.ExecutePipeline(context.Background(), "default/build", &logic.RunOptions{Branch: "dev"})
+			return err
+		}, want: clierror.Usage},
+		{name: "offline runtime environment", check: func() error { return s.CheckRuntimeEnvironment("kind/offline") }, want: clierror.API},
+		{name: "missing runtime environment", check: func() error { return s.CheckRuntimeEnvironment("kind/missing") }, want: clierror.Usage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.check(); clierror.KindOf(err) != tt.want {
+				t.Errorf("error = %v, want kind %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApproveBuild(t *testing.T) {
+	s, _, stop := newService(testScenario(cfapi.StatusPendingApproval), DefaultToken)
+	defer stop()
+	build, err := s.ExecutePipeline(context.Background(), "default/build", &logic.RunOptions{})
+	if err != nil {
+		t.Fatalf("ExecutePipeline() error = %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := s.ApproveBuild(context.Background(), build.ID, true); err != nil {
+		t.Fatalf("ApproveBuild() error = %v", err)
+	}
+	if err := s.WaitForBuild(context.Background(), build, waitOptions); err != nil {
+		t.Errorf("WaitForBuild() error = %v", err)
+	}
+}
+
+func TestCancelAndRestartBuild(t *testing.T) {
+	scenario := testScenario(cfapi.StatusSuccess)
+	scenario.Builds[0].Duration = Duration{time.Hour}
+	s, _, stop := newService(scenario, DefaultToken)
+	defer stop()
+	build, err := s.ExecutePipeline(context.Background(), "default/build", &logic.RunOptions{})
+	if err != nil {
+		t.Fatalf("ExecutePipeline() error = %v", err)
+	}
+	if err := s.CancelBuild(context.Background(), build.ID); err != nil {
+		t.Fatalf("CancelBuild() error = %v", err)
+	}
+	if err := s.WaitForBuild(context.Background(), build, waitOptions); build.Status != cfapi.StatusTerminated {
+		t.Errorf("WaitForBuild() status = %s, error = %v", build.Status, err)
+	}
+	restarted, err := s.RestartBuild(context.Background(), build.ID, true)
+	if err != nil {
+		t.Fatalf("RestartBuild() error = %v", err)
+	}
+	builds, _, err := s.ListBuilds(context.Background(), &logic.ListBuildsOptions{Pipeline: "default/build"})
+	if err != nil {
+		t.Fatalf("ListBuilds() error = %v", err)
+	}
+	if len(builds) != 2 || builds[0].ID != restarted.ID || builds[1].ID != build.ID {
+		t.Errorf("ListBuilds() = %v", builds)
+	}
+}
+
+func TestSmokeTestPipeline(t *testing.T) {
+	s, mock, stop := newService(&Scenario{RuntimeEnvironments: []*RuntimeEnvironment{{Name: "kind/default"}}}, DefaultToken)
+	defer stop()
+	name, deletePipeline, err := s.CreateSmokeTestPipeline(context.Background(), "kind/default")
+	if err != nil {
+		t.Fatalf("CreateSmokeTestPipeline() error = %v", err)
+	}
+	if _, err := s.ExecutePipeline(context.Background(), name, &logic.RunOptions{}); err != nil {
+		t.Errorf("ExecutePipeline() error = %v", err)
+	}
+	if err := deletePipeline(); err != nil {
+		t.Errorf("delete pipeline error = %v", err)
+	}
+	if len(mock.pipelines) != 0 {
+		t.Errorf("pipelines left after delete: %v", mock.pipelines)
+	}
+}
+
+func TestRegisterRuntimeEnvironment(t *testing.T) {
+	mock := New(&Options{})
+	server := httptest.NewServer(mock)
+	defer server.Close()
+	cf := codefresh.New(&codefresh.ClientOptions{Host: server.URL, Auth: codefresh.AuthOptions{Token: DefaultToken}})
+
+	if _, err := cf.RuntimeEnvironments().SignCertificate(&codefresh.SignCertificatesOptions{CSR: "csr"}); err != nil {
+		t.Fatalf("SignCertificate() error = %v", err)
+	}
+	re, err := cf.RuntimeEnvironments().Create(&codefresh.CreateRuntimeOptions{Cluster: "kind", Namespace: "codefresh"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if ok, err := cf.RuntimeEnvironments().Default(re.Metadata.Name); !ok {
+		t.Fatalf("Default() error = %v", err)
+	}
+	got, err := cf.RuntimeEnvironments().Get("kind/codefresh")
+	if err != nil || got.Metadata.Name != "kind/codefresh" {
+		t.Errorf("Get() = %+v, error = %v", got, err)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	s, _, stop := newService(testScenario(cfapi.StatusSuccess), "wrong")
+	defer stop()
+	if _, err := s.ListPipelines(context.Background(), &logic.ListPipelinesOptions{}); clierror.KindOf(err) != clierror.Auth {
+		t.Errorf("ListPipelines() error = %v, want an auth error", err)
+	}
+}
+
+func TestExportAndApplyPipelines(t *testing.T) {
+	s, mock, stop := newService(testScenario(cfapi.StatusSuccess), DefaultToken)
+	defer stop()
+
+	exported, err := s.ExportPipeline(context.Background(), "default/build")
+	if err != nil {
+		t.Fatalf("ExportPipeline() error = %v", err)
+	}
+	metadata := exported["metadata"].(map[string]interface{})
+	for _, field := range []string{"id", "created_at", "updated_at"} {
+		if _, ok := metadata[field]; ok {
+			t.Errorf("ExportPipeline() kept the managed field %s: %v", field, metadata)
+		}
+	}
+	if metadata["name"] != "default/build" || exported["kind"] != "pipeline" || exported["version"] != "1.0" {
+		t.Errorf("ExportPipeline() = %v", exported)
+	}
+	if _, err := s.ExportPipeline(context.Background(), "default/missing"); clierror.KindOf(err) != clierror.API {
+		t.Errorf("ExportPipeline() of a missing pipeline error = %v, want an API error", err)
+	}
+
+	changedSpec := map[string]interface{}{}
+	for k, v := range exported {
+		changedSpec[k] = v
+	}
+	changedSpec["spec"] = map[string]interface{}{"runtimeEnvironment": map[string]interface{}{"name": "kind/other"}}
+	newSpec := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "default/deploy", "project": "default"},
+		"spec":     map[string]interface{}{},
+	}
+	specs := []map[string]interface{}{changedSpec, newSpec}
+
+	diffs := []string{}
+	dryRun := &logic.ApplyOptions{DryRun: true, OnDiff: func(name string, diff string) {
+		diffs = append(diffs, name)
+	}}
+	results, err := s.ApplyPipelines(context.Background(), specs, dryRun)
+	if err != nil {
+		t.Fatalf("ApplyPipelines() dry run error = %v", err)
+	}
+	if len(results) != 2 || results[0].Action != logic.ApplyConfigured || results[1].Action != logic.ApplyCreated || !results[0].DryRun {
+		t.Errorf("ApplyPipelines() dry run = %v", results.Rows(false))
+	}
+	if len(diffs) != 2 {
+		t.Errorf("OnDiff() was called for %v, want both pipelines", diffs)
+	}
+	if _, ok := mock.pipelines["default/deploy"]; ok {
+		t.Error("the dry run created default/deploy")
+	}
+	if got, _ := s.ExportPipeline(context.Background(), "default/build"); !reflect.DeepEqual(got, exported) {
+		t.Errorf("the dry run changed default/build to %v", got)
+	}
+
+	results, err = s.ApplyPipelines(context.Background(), specs, &logic.ApplyOptions{})
+	if err != nil {
+		t.Fatalf("ApplyPipelines() error = %v", err)
+	}
+	if len(results) != 2 || results[0].Action != logic.ApplyConfigured || results[1].Action != logic.ApplyCreated || results[0].DryRun {
+		t.Errorf("ApplyPipelines() = %v", results.Rows(false))
+	}
+	got, err := s.ExportPipeline(context.Background(), "default/build")
+	if err != nil || !reflect.DeepEqual(got["spec"], changedSpec["spec"]) {
+		t.Errorf("default/build was not replaced, got %v, error = %v", got, err)
+	}
+	if _, ok := mock.pipelines["default/deploy"]; !ok {
+		t.Error("default/deploy was not created")
+	}
+
+	results, err = s.ApplyPipelines(context.Background(), specs, &logic.ApplyOptions{})
+	if err != nil {
+		t.Fatalf("ApplyPipelines() again error = %v", err)
+	}
+	for _, r := range results {
+		if r.Action != logic.ApplyUnchanged {
+			t.Errorf("ApplyPipelines() again = %v, want unchanged", results.Rows(false))
+		}
+	}
+}
+
+func TestBuildActionErrors(t *testing.T) {
+	running := testScenario(cfapi.StatusSuccess)
+	running.Builds[0].Duration = Duration{time.Hour}
+	tests := []struct {
+		name     string
+		scenario *Scenario
+		action   func(s *logic.Service, id string) error
+		want     clierror.Kind
+	}{
+		{
+			name:     "cancel a finished build",
+			scenario: testScenario(cfapi.StatusSuccess),
+			action:   func(s *logic.Service, id string) error { return s.CancelBuild(context.Background(), id) },
+			want:     clierror.Usage,
+		},
+		{
+			name:     "restart a running build",
+			scenario: running,
+			action: func(s *logic.Service, id string) error {
+				_, err := s.RestartBuild(context.Background(), id, false)
+				return err
+			},
+			want: clierror.Usage,
+		},
+		{
+			name:     "restart a successful build from the failed step",
+			scenario: testScenario(cfapi.StatusSuccess),
+			action: func(s *logic.Service, id string) error {
+				_, err := s.RestartBuild(context.Background(), id, true)
+				return err
+			},
+			want: clierror.Usage,
+		},
+		{
+			name:     "approve a build that does not wait for approval",
+			scenario: testScenario(cfapi.StatusError),
+			action:   func(s *logic.Service, id string) error { return s.ApproveBuild(context.Background(), id, true) },
+			want:     clierror.Usage,
+		},
+		{
+			name:     "deny a running build",
+			scenario: running,
+			action:   func(s *logic.Service, id string) error { return s.ApproveBuild(context.Background(), id, false) },
+			want:     clierror.Usage,
+		},
+		{
+			name:     "cancel a missing build",
+			scenario: testScenario(cfapi.StatusSuccess),
+			action:   func(s *logic.Service, id string) error { return s.CancelBuild(context.Background(), "missing") },
+			want:     clierror.API,
+		},
+		{
+			name:     "restart a missing build",
+			scenario: testScenario(cfapi.StatusSuccess),
+			action: func(s *logic.Service, id string) error {
+				_, err := s.RestartBuild(context.Background(), "missing", false)
+				return err
+			},
+			want: clierror.API,
+		},
+		{
+			name:     "approve a missing build",
+			scenario: testScenario(cfapi.StatusSuccess),
+			action:   func(s *logic.Service, id string) error { return s.ApproveBuild(context.Background(), "missing", true) },
+			want:     clierror.API,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, stop := newService(tt.scenario, DefaultToken)
+			defer stop()
+			build, err := s.ExecutePipeline(context.Background(), "default/build", &logic.RunOptions{})
+			if err != nil {
+				t.Fatalf("ExecutePipeline() error = %v", err)
+			}
+			time.Sleep(30 * time.Millisecond)
+			if err := tt.action(s, build.ID); clierror.KindOf(err) != tt.want {
+				t.Errorf("error = %v, want kind %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDenyBuild(t *testing.T) {
+	s, _, stop := newService(testScenario(cfapi.StatusPendingApproval), DefaultToken)
+	defer stop()
+	build, err := s.ExecutePipeline(context.Background(), "default/build", &logic.RunOptions{})
+	if err != nil {
+		t.Fatalf("ExecutePipeline() error = %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := s.ApproveBuild(context.Background(), build.ID, false); err != nil {
+		t.Fatalf("ApproveBuild() error = %v", err)
+	}
+	if err := s.WaitForBuild(context.Background(), build, waitOptions); clierror.KindOf(err) != clierror.PipelineFailed || build.Status != cfapi.StatusDenied {
+		t.Errorf("WaitForBuild() status = %s, error = %v", build.Status, err)
+	}
+	if err := s.ApproveBuild(context.Background(), build.ID, true); clierror.KindOf(err) != clierror.Usage {
+		t.Errorf("ApproveBuild() of a denied build error = %v, want a usage error", err)
+	}
+}
+
+func TestRestartFailedBuild(t *testing.T) {
+	s, mock, stop := newService(testScenario(cfapi.StatusError), DefaultToken)
+	defer stop()
+	build, err := s.ExecutePipeline(context.Background(), "default/build", &logic.RunOptions{})
+	if err != nil {
+		t.Fatalf("ExecutePipeline() error = %v", err)
+	}
+	if err := s.WaitForBuild(context.Background(), build, waitOptions); clierror.KindOf(err) != clierror.PipelineFailed {
+		t.Fatalf("WaitForBuild() error = %v", err)
+	}
+	restarted, err := s.RestartBuild(context.Background(), build.ID, true)
+	if err != nil {
+		t.Fatalf("RestartBuild() error = %v", err)
+	}
+	if restarted.ID == build.ID || restarted.Pipeline != "default/build" || mock.builds[restarted.ID] == nil {
+		t.Errorf("RestartBuild() = %+v", restarted)
+	}
+}
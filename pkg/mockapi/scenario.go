@@ -0,0 +1,114 @@
+package mockapi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/cfapi"
+	"sigs.k8s.io/yaml"
+)
+
+type (
+	// Scenario is the initial state of the mock and the outcomes of the builds
+	Scenario struct {
+		// Pipelines are full pipeline documents, as printed by pipelines export
+		Pipelines []map[string]interface{} `json:"pipelines"`
+		// RuntimeEnvironments that exist before create runtime registers new ones
+		RuntimeEnvironments []*RuntimeEnvironment `json:"runtimeEnvironments"`
+		// GitContexts are the names of the git integrations, the default is github
+		GitContexts []string `json:"gitContexts"`
+		// Repos lists the branches of the repositories (owner/name), repositories that
+		// are not listed have every branch
+		Repos map[string][]string `json:"repos"`
+		// Builds are the scripted outcomes, the first one that matches the pipeline is used
+		Builds []*Outcome `json:"builds"`
+	}
+
+	// RuntimeEnvironment of the scenario
+	RuntimeEnvironment struct {
+		Name    string `json:"name"`
+		Offline bool   `json:"offline"`
+	}
+
+	// Outcome scripts how the builds of a pipeline run
+	Outcome struct {
+		// Pipeline is the name of the pipeline, empty matches every pipeline
+		Pipeline string `json:"pipeline"`
+		// Status the build finishes with: success, error, terminated or pending-approval.
+		// A pending-approval build waits after its steps until it is approved or denied
+		Status string `json:"status"`
+		// Duration of the build, the steps split it evenly
+		Duration Duration `json:"duration"`
+		Steps    []*Step  `json:"steps"`
+	}
+
+	// Step of a scripted build
+	Step struct {
+		Name   string   `json:"name"`
+		Status string   `json:"status"`
+		Logs   []string `json:"logs"`
+	}
+
+	// Duration is a time.Duration written as 10s or 1m30s
+	Duration struct {
+		time.Duration
+	}
+)
+
+// defaultOutcome is used for the pipelines that have no outcome in the scenario
+var defaultOutcome = &Outcome{
+	Status:   cfapi.StatusSuccess,
+	Duration: Duration{10 * time.Second},
+	Steps: []*Step{
+		{Name: "main_clone", Status: cfapi.StatusSuccess, Logs: []string{"Cloning into 'repo'...\n"}},
+		{Name: "main", Status: cfapi.StatusSuccess, Logs: []string{"Running the pipeline\n", "Done\n"}},
+	},
+}
+
+// LoadScenario reads a yaml or json scenario file
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Scenario{}
+	if err := yaml.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("Failed to parse scenario %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// outcome returns the outcome of the builds of the pipeline
+func (s *Scenario) outcome(pipeline string) *Outcome {
+	for _, o := range s.Builds {
+		if o.Pipeline == "" || o.Pipeline == pipeline {
+			return o
+		}
+	}
+	return defaultOutcome
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%q", d.String())), nil
+}
+
+// UnmarshalJSON reads 10s or a number of seconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if len(s) > 1 && s[0] == '"' {
+		parsed, err := time.ParseDuration(s[1 : len(s)-1])
+		if err != nil {
+			return err
+		}
+		d.Duration = parsed
+		return nil
+	}
+	var seconds float64
+	if _, err := fmt.Sscan(s, &seconds); err != nil {
+		return fmt.Errorf("Invalid duration %s", s)
+	}
+	d.Duration = time.Duration(seconds * float64(time.Second))
+	return nil
+}
@@ -0,0 +1,123 @@
+package plugin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Prefix of the executables that are sharoncli plugins, sharoncli-<name> is run as sharoncli <name>
+const Prefix = "sharoncli-"
+
+type (
+	// Plugin is an executable found on the PATH
+	Plugin struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+		// Warning explains why the plugin is not run by sharoncli <name>, empty when it is
+		Warning string `json:"warning,omitempty"`
+	}
+
+	// Plugins in the order they were found
+	Plugins []*Plugin
+)
+
+// Find returns the plugins in the directories of path, a PATH value. A plugin shadowed by a plugin
+// with the same name in an earlier directory has a Warning
+func Find(path string) Plugins {
+	plugins := Plugins{}
+	found := map[string]*Plugin{}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			name := pluginName(f.Name())
+			if name == "" {
+				continue
+			}
+			p := &Plugin{
+				Name: name,
+				Path: filepath.Join(dir, f.Name()),
+			}
+			if !isExecutable(p.Path) {
+				continue
+			}
+			if first, ok := found[name]; ok {
+				p.Warning = fmt.Sprintf("shadowed by %s", first.Path)
+			} else {
+				found[name] = p
+			}
+			plugins = append(plugins, p)
+		}
+	}
+	return plugins
+}
+
+// Lookup returns the first plugin with the name in the directories of path, a PATH value, nil when there is none
+func Lookup(path string, name string) *Plugin {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, `/\`) {
+		return nil
+	}
+	file := Prefix + name
+	if runtime.GOOS == "windows" {
+		file += ".exe"
+	}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+		if p := filepath.Join(dir, file); isExecutable(p) {
+			return &Plugin{Name: name, Path: p}
+		}
+	}
+	return nil
+}
+
+// pluginName returns the subcommand of the file name, empty when the file is not a plugin
+func pluginName(file string) string {
+	if !strings.HasPrefix(file, Prefix) {
+		return ""
+	}
+	name := strings.TrimPrefix(file, Prefix)
+	if runtime.GOOS == "windows" {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if name == "" || strings.HasPrefix(name, "-") {
+		return ""
+	}
+	return name
+}
+
+// isExecutable follows symlinks, directories are not executable
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(path), ".exe")
+	}
+	return info.Mode()&0111 != 0
+}
+
+// Header implements printer.Table
+func (p Plugins) Header(wide bool) []string {
+	return []string{"NAME", "PATH", "WARNING"}
+}
+
+// Rows implements printer.Table
+func (p Plugins) Rows(wide bool) [][]string {
+	rows := [][]string{}
+	for _, plugin := range p {
+		rows = append(rows, []string{plugin.Name, plugin.Path, plugin.Warning})
+	}
+	return rows
+}
@@ -0,0 +1,83 @@
+package plugin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir string, name string, mode os.FileMode) {
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	root, err := ioutil.TempDir("", "plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	first, second := filepath.Join(root, "first"), filepath.Join(root, "second")
+	os.Mkdir(first, 0755)
+	os.Mkdir(second, 0755)
+	writeFile(t, first, "sharoncli-seed", 0755)
+	writeFile(t, first, "sharoncli-notes", 0644)
+	writeFile(t, first, "kubectl-seed", 0755)
+	writeFile(t, second, "sharoncli-seed", 0755)
+	writeFile(t, second, "sharoncli-label-nodes", 0755)
+	os.Mkdir(filepath.Join(second, "sharoncli-dir"), 0755)
+
+	plugins := Find(strings.Join([]string{first, filepath.Join(root, "missing"), second}, string(os.PathListSeparator)))
+	expected := Plugins{
+		{Name: "seed", Path: filepath.Join(first, "sharoncli-seed")},
+		{Name: "label-nodes", Path: filepath.Join(second, "sharoncli-label-nodes")},
+		{Name: "seed", Path: filepath.Join(second, "sharoncli-seed"), Warning: "shadowed by " + filepath.Join(first, "sharoncli-seed")},
+	}
+	if len(plugins) != len(expected) {
+		t.Fatalf("expected %d plugins, got %v", len(expected), plugins.Rows(false))
+	}
+	for i, p := range plugins {
+		if *p != *expected[i] {
+			t.Errorf("plugin %d: expected %+v, got %+v", i, *expected[i], *p)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	root, err := ioutil.TempDir("", "plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	first, second := filepath.Join(root, "first"), filepath.Join(root, "second")
+	os.Mkdir(first, 0755)
+	os.Mkdir(second, 0755)
+	writeFile(t, first, "sharoncli-notes", 0644)
+	writeFile(t, second, "sharoncli-notes", 0755)
+	writeFile(t, second, "sharoncli-seed", 0755)
+	writeFile(t, first, "sharoncli-seed", 0755)
+	path := strings.Join([]string{first, second}, string(os.PathListSeparator))
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"seed", filepath.Join(first, "sharoncli-seed")},
+		{"notes", filepath.Join(second, "sharoncli-notes")},
+		{"missing", ""},
+		{"../first/sharoncli-seed", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		p := Lookup(path, test.name)
+		switch {
+		case test.path == "" && p != nil:
+			t.Errorf("%q: expected no plugin, got %+v", test.name, *p)
+		case test.path != "" && (p == nil || p.Path != test.path || p.Name != test.name):
+			t.Errorf("%q: expected %s, got %+v", test.name, test.path, p)
+		}
+	}
+}
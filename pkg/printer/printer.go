@@ -0,0 +1,184 @@
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/olekukonko/tablewriter"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// FormatTable prints the default columns
+	FormatTable = "table"
+	// FormatWide prints all the columns
+	FormatWide = "wide"
+	// FormatJSON prints the object as indented json
+	FormatJSON = "json"
+	// FormatYAML prints the object as yaml
+	FormatYAML = "yaml"
+	// FormatJSONPath prefix of the jsonpath=<template> format
+	FormatJSONPath = "jsonpath"
+	// FormatGoTemplate prefix of the go-template=<template> format
+	FormatGoTemplate = "go-template"
+)
+
+// Formats lists the supported values of the output option
+var Formats = []string{FormatTable, FormatWide, FormatJSON, FormatYAML, FormatJSONPath + "=...", FormatGoTemplate + "=..."}
+
+type (
+	// Printer writes objects in a single format
+	Printer interface {
+		Print(obj interface{}) error
+	}
+
+	// Table is implemented by objects that can be printed as table or wide
+	Table interface {
+		Header(wide bool) []string
+		Rows(wide bool) [][]string
+	}
+
+	tablePrinter struct {
+		w    io.Writer
+		wide bool
+	}
+
+	jsonPrinter struct {
+		w io.Writer
+	}
+
+	yamlPrinter struct {
+		w io.Writer
+	}
+
+	jsonPathPrinter struct {
+		w  io.Writer
+		jp *jsonpath.JSONPath
+	}
+
+	templatePrinter struct {
+		w   io.Writer
+		tpl *template.Template
+	}
+)
+
+// New creates a printer for one of the Formats, the default is table
+func New(format string, w io.Writer) (Printer, error) {
+	name, arg := format, ""
+	if i := strings.Index(format, "="); i >= 0 {
+		name, arg = format[:i], format[i+1:]
+	}
+	switch name {
+	case "", FormatTable:
+		return &tablePrinter{w: w}, nil
+	case FormatWide:
+		return &tablePrinter{w: w, wide: true}, nil
+	case FormatJSON:
+		return &jsonPrinter{w}, nil
+	case FormatYAML:
+		return &yamlPrinter{w}, nil
+	case FormatJSONPath:
+		if arg == "" {
+			return nil, fmt.Errorf("jsonpath format requires a template, e.g. jsonpath={.id}")
+		}
+		jp := jsonpath.New("output")
+		if err := jp.Parse(arg); err != nil {
+			return nil, fmt.Errorf("Failed to parse jsonpath template: %v", err)
+		}
+		return &jsonPathPrinter{w, jp}, nil
+	case FormatGoTemplate:
+		if arg == "" {
+			return nil, fmt.Errorf("go-template format requires a template, e.g. go-template={{.id}}")
+		}
+		tpl, err := template.New("output").Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse go-template: %v", err)
+		}
+		return &templatePrinter{w, tpl}, nil
+	}
+	return nil, fmt.Errorf("Unknown output format %q, supported formats are %s", format, strings.Join(Formats, "|"))
+}
+
+// NewTable creates the table writer used for the human readable output
+func NewTable(w io.Writer) *tablewriter.Table {
+	table := tablewriter.NewWriter(w)
+	table.SetBorder(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetRowLine(false)
+	table.SetHeaderLine(false)
+	table.SetColumnSeparator(" ")
+	table.SetColWidth(100)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoWrapText(false)
+	return table
+}
+
+func (p *tablePrinter) Print(obj interface{}) error {
+	t, ok := obj.(Table)
+	if !ok {
+		return (&yamlPrinter{p.w}).Print(obj)
+	}
+	table := NewTable(p.w)
+	table.SetHeader(t.Header(p.wide))
+	table.AppendBulk(t.Rows(p.wide))
+	table.Render()
+	return nil
+}
+
+func (p *jsonPrinter) Print(obj interface{}) error {
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(p.w, string(data))
+	return err
+}
+
+func (p *yamlPrinter) Print(obj interface{}) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	_, err = p.w.Write(data)
+	return err
+}
+
+func (p *jsonPathPrinter) Print(obj interface{}) error {
+	data, err := toGeneric(obj)
+	if err != nil {
+		return err
+	}
+	if err := p.jp.Execute(p.w, data); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(p.w)
+	return err
+}
+
+func (p *templatePrinter) Print(obj interface{}) error {
+	data, err := toGeneric(obj)
+	if err != nil {
+		return err
+	}
+	if err := p.tpl.Execute(p.w, data); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(p.w)
+	return err
+}
+
+// toGeneric converts obj to maps and slices so templates use the json field names
+func toGeneric(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	err = json.Unmarshal(data, &generic)
+	return generic, err
+}
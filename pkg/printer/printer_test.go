@@ -0,0 +1,73 @@
+package printer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+type items []item
+
+func (i items) Header(wide bool) []string {
+	if wide {
+		return []string{"NAME", "STATUS"}
+	}
+	return []string{"NAME"}
+}
+
+func (i items) Rows(wide bool) [][]string {
+	rows := [][]string{}
+	for _, it := range i {
+		if wide {
+			rows = append(rows, []string{it.Name, it.Status})
+		} else {
+			rows = append(rows, []string{it.Name})
+		}
+	}
+	return rows
+}
+
+func TestPrint(t *testing.T) {
+	obj := items{{"first", "success"}, {"second", "error"}}
+	tests := map[string]string{
+		"json":                               "\"status\": \"error\"",
+		"yaml":                               "- name: first\n  status: success\n",
+		"jsonpath={[*].name}":                "first second\n",
+		"go-template={{len .}}":              "2\n",
+		"wide":                               "success",
+		"go-template={{(index . 1).status}}": "error\n",
+	}
+	for format, expected := range tests {
+		buf := &bytes.Buffer{}
+		p, err := New(format, buf)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if err := p.Print(obj); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("%s: expected %q in %q", format, expected, buf.String())
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	p, _ := New("table", buf)
+	p.Print(obj)
+	if strings.Contains(buf.String(), "success") {
+		t.Errorf("table output should not contain the wide columns: %q", buf.String())
+	}
+}
+
+func TestNewUnknownFormat(t *testing.T) {
+	for _, format := range []string{"xml", "jsonpath=", "go-template={{"} {
+		if _, err := New(format, &bytes.Buffer{}); err == nil {
+			t.Errorf("%s: expected an error", format)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	spinnerFrames   = "⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏"
+	spinnerInterval = 100 * time.Millisecond
+	clearLine       = "\r\033[K"
+)
+
+type (
+	// Console is the writer the Reporter draws on. Everything else that writes to the same terminal,
+	// like the console log, writes through it so its lines do not mix with the spinner
+	Console struct {
+		mu     sync.Mutex
+		w      io.Writer
+		status string
+	}
+
+	// Options of the Reporter
+	Options struct {
+		Console *Console
+		// Interactive draws a spinner with the elapsed time of the phase, otherwise a timestamped
+		// line is printed when a phase starts and when it ends
+		Interactive bool
+		// Disabled reports nothing, e.g. when the console log is json
+		Disabled bool
+	}
+
+	// Reporter shows the phases of a long operation, one at a time
+	Reporter struct {
+		console     *Console
+		interactive bool
+		disabled    bool
+		now         func() time.Time
+	}
+
+	// Phase is a step of the operation, it ends with Done or Warn
+	Phase struct {
+		r       *Reporter
+		name    string
+		started time.Time
+
+		mu     sync.Mutex
+		paused bool
+		stop   chan struct{}
+		done   chan struct{}
+	}
+)
+
+// NewConsole creates a Console that writes to w
+func NewConsole(w io.Writer) *Console {
+	return &Console{w: w}
+}
+
+// Write clears the spinner line, writes p and draws the spinner line again after a complete line
+func (c *Console) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status != "" {
+		fmt.Fprint(c.w, clearLine)
+	}
+	n, err := c.w.Write(p)
+	if c.status != "" && len(p) > 0 && p[len(p)-1] == '\n' {
+		fmt.Fprint(c.w, c.status)
+	}
+	return n, err
+}
+
+// setStatus replaces the spinner line, empty removes it
+func (c *Console) setStatus(status string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status != "" || status != "" {
+		fmt.Fprint(c.w, clearLine+status)
+	}
+	c.status = status
+}
+
+// New creates a Reporter
+func New(opt *Options) *Reporter {
+	return &Reporter{
+		console:     opt.Console,
+		interactive: opt.Interactive,
+		disabled:    opt.Disabled,
+		now:         time.Now,
+	}
+}
+
+// Start starts a phase
+func (r *Reporter) Start(name string) *Phase {
+	p := &Phase{
+		r:       r,
+		name:    name,
+		started: r.now(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	switch {
+	case r.disabled:
+		close(p.done)
+	case r.interactive:
+		go p.spin()
+	default:
+		close(p.done)
+		r.printf("%s %s...\n", r.timestamp(), name)
+	}
+	return p
+}
+
+// Pause removes the spinner until the phase ends, for phases that show their own progress
+func (p *Phase) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = true
+	if p.r.interactive && !p.r.disabled {
+		p.r.console.setStatus("")
+	}
+}
+
+// Done ends the phase, it failed when err is not nil. It returns err
+func (p *Phase) Done(err error) error {
+	if err != nil {
+		p.end("✗", "failed", err.Error())
+		return err
+	}
+	p.end("✓", "done", "")
+	return nil
+}
+
+// Warn ends a phase that failed without failing the operation
+func (p *Phase) Warn(message string) {
+	p.end("!", "warning", message)
+}
+
+func (p *Phase) end(symbol string, result string, message string) {
+	close(p.stop)
+	<-p.done
+	if p.r.disabled {
+		return
+	}
+	elapsed := formatElapsed(p.r.now().Sub(p.started))
+	if message != "" {
+		message = ": " + message
+	}
+	if p.r.interactive {
+		p.r.printf("%s %s (%s)%s\n", symbol, p.name, elapsed, message)
+		return
+	}
+	p.r.printf("%s %s %s (%s)%s\n", p.r.timestamp(), p.name, result, elapsed, message)
+}
+
+// spin redraws the spinner line until the phase ends
+func (p *Phase) spin() {
+	defer close(p.done)
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+	frames := []rune(spinnerFrames)
+	for i := 0; ; i++ {
+		p.mu.Lock()
+		if !p.paused {
+			p.r.console.setStatus(fmt.Sprintf("%c %s (%s)", frames[i%len(frames)], p.name, formatElapsed(p.r.now().Sub(p.started))))
+		}
+		p.mu.Unlock()
+		select {
+		case <-p.stop:
+			p.r.console.setStatus("")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Reporter) printf(format string, a ...interface{}) {
+	fmt.Fprintf(r.console, format, a...)
+}
+
+func (r *Reporter) timestamp() string {
+	return r.now().UTC().Format(time.RFC3339)
+}
+
+func formatElapsed(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,68 @@
+package progress
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReporterLines(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := New(&Options{Console: NewConsole(buf)})
+	now := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	p := r.Start("Create cluster")
+	now = now.Add(90 * time.Second)
+	p.Done(nil)
+	p = r.Start("Install venona")
+	now = now.Add(2 * time.Second)
+	p.Done(errors.New("forbidden"))
+	p = r.Start("Wait for the agent")
+	now = now.Add(time.Minute)
+	p.Warn("offline")
+
+	expected := `2019-10-01T12:00:00Z Create cluster...
+2019-10-01T12:01:30Z Create cluster done (1m30s)
+2019-10-01T12:01:30Z Install venona...
+2019-10-01T12:01:32Z Install venona failed (2s): forbidden
+2019-10-01T12:01:32Z Wait for the agent...
+2019-10-01T12:02:32Z Wait for the agent warning (1m0s): offline
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestReporterInteractive(t *testing.T) {
+	buf := &bytes.Buffer{}
+	console := NewConsole(buf)
+	r := New(&Options{Console: console, Interactive: true})
+	p := r.Start("Create cluster")
+	time.Sleep(3 * spinnerInterval)
+	console.Write([]byte("a log line\n"))
+	p.Done(nil)
+
+	out := buf.String()
+	if !strings.Contains(out, clearLine+"⠋ Create cluster (0s)") {
+		t.Errorf("expected the spinner, got %q", out)
+	}
+	if !strings.Contains(out, clearLine+"a log line\n") {
+		t.Errorf("expected the log line on a cleared line, got %q", out)
+	}
+	if !strings.HasSuffix(out, clearLine+"✓ Create cluster (0s)\n") {
+		t.Errorf("expected the done line to replace the spinner, got %q", out)
+	}
+}
+
+func TestReporterDisabled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := New(&Options{Console: NewConsole(buf), Interactive: true, Disabled: true})
+	p := r.Start("Create cluster")
+	p.Pause()
+	if err := p.Done(errors.New("failed")); err == nil || buf.Len() != 0 {
+		t.Errorf("expected no output and the error, got %q and %v", buf.String(), err)
+	}
+}
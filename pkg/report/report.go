@@ -0,0 +1,217 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/logic"
+)
+
+const (
+	// FormatJUnit is a JUnit XML report
+	FormatJUnit = "junit"
+	// FormatTAP is a Test Anything Protocol (version 13) report
+	FormatTAP = "tap"
+
+	statusSuccess = "success"
+	// buildCase is the name of the test case of the build itself
+	buildCase = "pipeline"
+)
+
+type (
+	// Report is a --report value, an empty Path means stdout
+	Report struct {
+		Format string
+		Path   string
+	}
+
+	testCase struct {
+		suite    string
+		name     string
+		duration time.Duration
+		passed   bool
+		skipped  bool
+		message  string
+		url      string
+	}
+
+	junitTestSuites struct {
+		XMLName  xml.Name         `xml:"testsuites"`
+		Name     string           `xml:"name,attr"`
+		Tests    int              `xml:"tests,attr"`
+		Failures int              `xml:"failures,attr"`
+		Time     string           `xml:"time,attr"`
+		Suites   []junitTestSuite `xml:"testsuite"`
+	}
+
+	junitTestSuite struct {
+		Name       string          `xml:"name,attr"`
+		Tests      int             `xml:"tests,attr"`
+		Failures   int             `xml:"failures,attr"`
+		Skipped    int             `xml:"skipped,attr"`
+		Time       string          `xml:"time,attr"`
+		Properties []junitProperty `xml:"properties>property,omitempty"`
+		Cases      []junitTestCase `xml:"testcase"`
+	}
+
+	junitProperty struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	}
+
+	junitTestCase struct {
+		Name      string        `xml:"name,attr"`
+		ClassName string        `xml:"classname,attr"`
+		Time      string        `xml:"time,attr"`
+		Failure   *junitFailure `xml:"failure,omitempty"`
+		Skipped   *struct{}     `xml:"skipped,omitempty"`
+		SystemOut string        `xml:"system-out,omitempty"`
+	}
+
+	junitFailure struct {
+		Message string `xml:"message,attr"`
+		Text    string `xml:",chardata"`
+	}
+)
+
+// Parse parses a --report value: junit=path.xml, tap or tap=path.tap
+func Parse(value string) (*Report, error) {
+	format, path := value, ""
+	if i := strings.Index(value, "="); i >= 0 {
+		format, path = value[:i], value[i+1:]
+	}
+	if format != FormatJUnit && format != FormatTAP {
+		return nil, fmt.Errorf("Unknown report %q, supported reports are %s[=path] and %s[=path]", value, FormatJUnit, FormatTAP)
+	}
+	return &Report{Format: format, Path: path}, nil
+}
+
+// Write writes the report of the builds
+func (r *Report) Write(w io.Writer, builds logic.Builds) error {
+	if r.Format == FormatJUnit {
+		return WriteJUnit(w, builds)
+	}
+	return WriteTAP(w, builds)
+}
+
+// testCases turns a build into one test case for the build and one per step
+func testCases(b *logic.Build) []*testCase {
+	cases := []*testCase{{
+		suite:    b.Pipeline,
+		name:     buildCase,
+		duration: b.Duration,
+		passed:   b.Status == statusSuccess,
+		message:  b.Reason,
+		url:      b.URL,
+	}}
+	for _, step := range b.Steps {
+		c := &testCase{
+			suite:    b.Pipeline,
+			name:     step.Name,
+			duration: step.Duration,
+			passed:   step.Status == statusSuccess,
+			url:      b.URL,
+		}
+		switch step.Status {
+		case statusSuccess:
+		case "error", "failure":
+			c.message = fmt.Sprintf("step %s failed", step.Name)
+		default:
+			// steps that did not run because an earlier step failed
+			c.skipped = true
+			c.message = fmt.Sprintf("step %s is %s", step.Name, step.Status)
+		}
+		cases = append(cases, c)
+	}
+	return cases
+}
+
+// WriteJUnit writes one test suite per build
+func WriteJUnit(w io.Writer, builds logic.Builds) error {
+	suites := &junitTestSuites{Name: "sharoncli test runtime"}
+	var total time.Duration
+	for _, b := range builds {
+		suite := junitTestSuite{
+			Name: b.Pipeline,
+			Time: seconds(b.Duration),
+			Properties: []junitProperty{
+				{Name: "build.id", Value: b.ID},
+				{Name: "build.url", Value: b.URL},
+				{Name: "build.status", Value: b.Status},
+			},
+		}
+		for _, c := range testCases(b) {
+			tc := junitTestCase{
+				Name:      c.name,
+				ClassName: c.suite,
+				Time:      seconds(c.duration),
+				SystemOut: c.url,
+			}
+			switch {
+			case c.skipped:
+				tc.Skipped = &struct{}{}
+				suite.Skipped++
+			case !c.passed:
+				tc.Failure = &junitFailure{
+					Message: c.message,
+					Text:    fmt.Sprintf("%s\n%s", c.message, c.url),
+				}
+				suite.Failures++
+			}
+			suite.Tests++
+			suite.Cases = append(suite.Cases, tc)
+		}
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Suites = append(suites.Suites, suite)
+		total += b.Duration
+	}
+	suites.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// WriteTAP writes one test point per test case
+func WriteTAP(w io.Writer, builds logic.Builds) error {
+	cases := []*testCase{}
+	for _, b := range builds {
+		cases = append(cases, testCases(b)...)
+	}
+	lines := []string{"TAP version 13", fmt.Sprintf("1..%d", len(cases))}
+	for i, c := range cases {
+		status := "ok"
+		if !c.passed && !c.skipped {
+			status = "not ok"
+		}
+		line := fmt.Sprintf("%s %d - %s %s", status, i+1, c.suite, c.name)
+		if c.skipped {
+			line += " # SKIP " + c.message
+		}
+		lines = append(lines, line, "  ---")
+		if !c.passed && !c.skipped {
+			lines = append(lines, fmt.Sprintf("  message: %q", c.message))
+		}
+		lines = append(lines,
+			fmt.Sprintf("  duration_ms: %d", c.duration/time.Millisecond),
+			fmt.Sprintf("  url: %s", c.url),
+			"  ...")
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,76 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sharon-vendrov/sharoncli/pkg/logic"
+)
+
+var builds = logic.Builds{
+	{
+		ID:       "1",
+		Pipeline: "default/ok",
+		URL:      "https://g.codefresh.io/build/1",
+		Status:   "success",
+		Duration: time.Minute,
+		Steps: []*logic.BuildStep{
+			{Name: "clone", Status: "success", Duration: time.Second},
+		},
+	},
+	{
+		ID:       "2",
+		Pipeline: "default/broken",
+		URL:      "https://g.codefresh.io/build/2",
+		Status:   "error",
+		Duration: 2 * time.Minute,
+		Reason:   "step build failed",
+		Steps: []*logic.BuildStep{
+			{Name: "build", Status: "error", Duration: time.Second},
+			{Name: "push", Status: "pending"},
+		},
+	},
+}
+
+func TestWriteJUnit(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := WriteJUnit(buf, builds); err != nil {
+		t.Fatal(err)
+	}
+	suites := &junitTestSuites{}
+	if err := xml.Unmarshal(buf.Bytes(), suites); err != nil {
+		t.Fatalf("invalid xml: %v\n%s", err, buf.String())
+	}
+	if suites.Tests != 5 || suites.Failures != 2 || len(suites.Suites) != 2 {
+		t.Errorf("expected 5 tests with 2 failures in 2 suites, got %d tests, %d failures, %d suites", suites.Tests, suites.Failures, len(suites.Suites))
+	}
+	if suites.Suites[1].Skipped != 1 || suites.Suites[1].Cases[0].Failure.Message != "step build failed" {
+		t.Errorf("unexpected suite %+v", suites.Suites[1])
+	}
+}
+
+func TestWriteTAP(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := WriteTAP(buf, builds); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, expected := range []string{"1..5\n", "ok 1 - default/ok pipeline\n", "not ok 3 - default/broken pipeline\n", "ok 5 - default/broken push # SKIP"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in\n%s", expected, out)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	r, err := Parse("junit=out/report.xml")
+	if err != nil || r.Format != FormatJUnit || r.Path != "out/report.xml" {
+		t.Errorf("unexpected report %+v, %v", r, err)
+	}
+	if _, err := Parse("html"); err == nil {
+		t.Error("expected an error for an unknown report")
+	}
+}
@@ -0,0 +1,79 @@
+package state
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type (
+	// State is what sharoncli remembers between runs
+	State struct {
+		// Runtimes created by create runtime by kube context
+		Runtimes map[string]*Runtime `json:"runtimes"`
+		// LastContext is the kube context of the last created runtime
+		LastContext string `json:"lastContext"`
+
+		path string
+	}
+
+	// Runtime created by create runtime
+	Runtime struct {
+		KubeContext        string    `json:"kubeContext"`
+		KubeConfig         string    `json:"kubeConfig"`
+		Namespace          string    `json:"namespace"`
+		RuntimeEnvironment string    `json:"runtimeEnvironment"`
+		CreatedAt          time.Time `json:"createdAt"`
+	}
+)
+
+// Load reads the state from path, a missing file is an empty state
+func Load(path string) (*State, error) {
+	s := &State{
+		Runtimes: map[string]*Runtime{},
+		path:     path,
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.Runtimes == nil {
+		s.Runtimes = map[string]*Runtime{}
+	}
+	return s, nil
+}
+
+// Save writes the state back to the file it was loaded from
+func (s *State) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.path, data, 0644)
+}
+
+// AddRuntime records r as the last created runtime
+func (s *State) AddRuntime(r *Runtime) {
+	s.Runtimes[r.KubeContext] = r
+	s.LastContext = r.KubeContext
+}
+
+// Runtime returns the runtime created for the kube context, or the last created runtime when
+// kubeContext is empty. nil is returned when there is no such runtime
+func (s *State) Runtime(kubeContext string) *Runtime {
+	if kubeContext == "" {
+		kubeContext = s.LastContext
+	}
+	return s.Runtimes[kubeContext]
+}